@@ -1,5 +1,8 @@
 (function(){
-  function Omega() {}
+  // The params of the recording are set by the page template
+  function Omega() {
+    this.params = window.OMEGA_PARAMS || {}
+  }
 
   Omega.prototype.send = function send(message) {
     console.info(JSON.stringify({
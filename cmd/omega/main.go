@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	"gux.codes/omega/pkg/chrome"
@@ -81,9 +82,7 @@ func main() {
 							}
 
 							// Play the animation
-							shell.Play(recordingPath, *options)
-
-							return nil
+							return shell.Play(recordingPath, *options)
 						},
 					},
 					// Record
@@ -132,6 +131,17 @@ func main() {
 								Destination: &outputPath,
 								EnvVars: []string{"OMEGA_SHELL_RECORD_OUTPUTPATH"},
 							},
+							&cli.StringSliceFlag{
+								Name: "pane",
+								Aliases: []string{"p"},
+								Usage: "command to run on an additional pane, switch panes with CTRL+]",
+								EnvVars: []string{"OMEGA_SHELL_RECORD_PANE"},
+							},
+							&cli.BoolFlag{
+								Name: "pausePlaceholder",
+								Usage: "insert a paused frame where the recording was paused with CTRL+\\",
+								EnvVars: []string{"OMEGA_SHELL_RECORD_PAUSEPLACEHOLDER"},
+							},
 						},
 						Action: func(c *cli.Context) error {
 							specification := shell.NewShellSpecification()
@@ -158,6 +168,18 @@ func main() {
 							if outputPath := c.String("outputPath"); outputPath != "" {
 								specification.OutputPath = outputPath
 							}
+							specification.PausePlaceholder = c.Bool("pausePlaceholder")
+
+							// Record every pane if additional panes were requested
+							if panes := c.StringSlice("pane"); len(panes) > 0 {
+								specifications := []shell.ShellSpecification{*specification}
+								for _, command := range panes {
+									pane := *specification
+									pane.Command = command
+									specifications = append(specifications, pane)
+								}
+								return shell.MultiShell(specifications)
+							}
 
 							// Start recording the shell
 							if err := shell.Shell(*specification); err != nil {
@@ -167,6 +189,56 @@ func main() {
 							return nil
 						},
 					},
+					// Convert
+					{
+						Name: "convert",
+						Aliases: []string{"c"},
+						Usage: "converts a recording from or to the ttyrec and script formats",
+						UsageText: "omega shell convert [OPTIONS] INPUT OUTPUT",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "from",
+								Usage: "input recording format (omega, ttyrec or script)",
+								DefaultText: "detected from the input extension",
+								EnvVars: []string{"OMEGA_SHELL_CONVERT_FROM"},
+							},
+							&cli.StringFlag{
+								Name: "to",
+								Usage: "output recording format (omega, ttyrec or script)",
+								DefaultText: "detected from the output extension",
+								EnvVars: []string{"OMEGA_SHELL_CONVERT_TO"},
+							},
+							&cli.StringFlag{
+								Name: "timing",
+								Usage: "timing file of a script input recording",
+								DefaultText: "INPUT.timing",
+								EnvVars: []string{"OMEGA_SHELL_CONVERT_TIMING"},
+							},
+							&cli.StringFlag{
+								Name: "outputTiming",
+								Usage: "timing file of a script output recording",
+								DefaultText: "OUTPUT.timing",
+								EnvVars: []string{"OMEGA_SHELL_CONVERT_OUTPUTTIMING"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							// Check if the input and output paths were supplied
+							if c.NArg() != 2 {
+								return errors.New("an input and an output path must be supplied")
+							}
+
+							// Create the ConvertOptions object
+							options := shell.ConvertOptions{
+								From: c.String("from"),
+								To: c.String("to"),
+								Timing: c.String("timing"),
+								OutputTiming: c.String("outputTiming"),
+							}
+
+							// Convert the recording
+							return shell.Convert(c.Args().Get(0), c.Args().Get(1), options)
+						},
+					},
 				},
 			},
 			// Chrome
@@ -185,9 +257,15 @@ func main() {
 								Name: "duration",
 								Aliases: []string{"d"},
 								Value: 1000,
-								Usage: "duration of the recording",
+								Usage: "duration of the recording, or its maximum if the page sends Omega.js commands",
 								EnvVars: []string{"OMEGA_CHROME_RECORD_DURATION"},
 							},
+							&cli.Float64Flag{
+								Name: "fps",
+								Value: chrome.DEFAULT_FPS,
+								Usage: "frame rate of the recording, like 24, 25 or 29.97",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FPS"},
+							},
 							&cli.Int64Flag{
 								Name: "workers",
 								Aliases: []string{"w"},
@@ -195,6 +273,105 @@ func main() {
 								Usage: "amount of concurrent browsers recording frames",
 								EnvVars: []string{"OMEGA_CHROME_RECORD_WORKERS"},
 							},
+							&cli.IntFlag{
+								Name: "retries",
+								Value: chrome.DEFAULT_RETRIES,
+								Usage: "times a frame is captured again after an error",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_RETRIES"},
+							},
+							&cli.StringFlag{
+								Name: "output",
+								Aliases: []string{"o"},
+								Usage: "path of the recording, or directory of a png-sequence",
+								DefaultText: "/tmp/out.{{ extension }}",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_OUTPUT"},
+							},
+							&cli.StringFlag{
+								Name: "preset",
+								Aliases: []string{"p"},
+								Value: chrome.DEFAULT_PRESET,
+								Usage: "ffmpeg container and codec preset (" + strings.Join(chrome.PresetNames(), ", ") + ")",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_PRESET"},
+							},
+							&cli.StringFlag{
+								Name: "ffmpegArgs",
+								Usage: "extra ffmpeg output arguments, quoted like a shell does, like \"-crf 18\"",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FFMPEGARGS"},
+							},
+							&cli.IntFlag{
+								Name: "startFrame",
+								Usage: "first frame to record",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_STARTFRAME"},
+							},
+							&cli.IntFlag{
+								Name: "endFrame",
+								Usage: "last frame to record, included",
+								DefaultText: "the last frame of the duration",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_ENDFRAME"},
+							},
+							&cli.StringFlag{
+								Name: "from",
+								Usage: "time of the first frame to record, like 2.5s, instead of --startFrame",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FROM"},
+							},
+							&cli.StringFlag{
+								Name: "to",
+								Usage: "time of the last frame to record, included, like 4s, instead of --endFrame",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_TO"},
+							},
+							&cli.StringFlag{
+								Name: "frames",
+								Usage: "directory where every frame is also saved as a PNG file",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FRAMES"},
+							},
+							&cli.StringFlag{
+								Name: "zip",
+								Usage: "zip archive where every frame is also saved as a PNG file",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_ZIP"},
+							},
+							&cli.BoolFlag{
+								Name: "noVideo",
+								Usage: "skip the ffmpeg encoding, keeping only the --frames and --zip outputs",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_NOVIDEO"},
+							},
+							&cli.StringFlag{
+								Name: "url",
+								Usage: "URL of the page to record",
+								DefaultText: "the handler of the web server",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_URL"},
+							},
+							&cli.StringFlag{
+								Name: "file",
+								Usage: "local HTML file to record, served with the files next to it",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FILE"},
+							},
+							&cli.StringFlag{
+								Name: "dir",
+								Usage: "local directory to record, served as static files from its index.html",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_DIR"},
+							},
+							&cli.StringFlag{
+								Name: "entryPoint",
+								Aliases: []string{"e"},
+								Usage: "entrypoint of an esbuild project to build and record, as seen on omega chrome dev",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_ENTRYPOINT"},
+							},
+							&cli.StringFlag{
+								Name: "data",
+								Usage: "JSON or YAML file passed to the templates and exposed on the page as Omega.params.data",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_DATA"},
+							},
+							&cli.BoolFlag{
+								Name: "omega",
+								Value: true,
+								Usage: "bound the recording with the Omega.js start, stop and done commands of the page",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_OMEGA"},
+							},
+							&cli.BoolFlag{
+								Name: "transparent",
+								Usage: "record over a transparent background, forcing an alpha preset",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_TRANSPARENT"},
+							},
 							&cli.Float64Flag{
 								Name: "width",
 								Aliases: []string{"W"},
@@ -209,14 +386,158 @@ func main() {
 								Usage: "height of the recording",
 								EnvVars: []string{"OMEGA_CHROME_RECORD_HEIGHT"},
 							},
+							&cli.Float64Flag{
+								Name: "deviceScaleFactor",
+								Aliases: []string{"scale"},
+								Usage: "device pixels per CSS pixel, like 2 to capture a 1920x1080 layout in 4K",
+								DefaultText: "1, or the ratio of the output size to the viewport",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_DEVICESCALEFACTOR"},
+							},
+							&cli.Int64Flag{
+								Name: "outputWidth",
+								Usage: "width of the captured frames, keeping the layout of the viewport",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_OUTPUTWIDTH"},
+							},
+							&cli.Int64Flag{
+								Name: "outputHeight",
+								Usage: "height of the captured frames, keeping the layout of the viewport",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_OUTPUTHEIGHT"},
+							},
+							&cli.IntFlag{
+								Name: "supersample",
+								Usage: "capture the frames at N times their resolution and downscale them, smoothing lines and text",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_SUPERSAMPLE"},
+							},
+							&cli.StringFlag{
+								Name: "format",
+								Value: "png",
+								Usage: "format of the captured frames (png, jpeg, webp)",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_FORMAT"},
+							},
+							&cli.Int64Flag{
+								Name: "quality",
+								Usage: "quality of the jpeg and webp frames, from 0 to 100",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_QUALITY"},
+							},
+							&cli.StringFlag{
+								Name: "selector",
+								Usage: "capture only the element that matches the CSS selector",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_SELECTOR"},
+							},
+							&cli.StringFlag{
+								Name: "clip",
+								Usage: "capture only a region of the page, as x,y,width,height in CSS pixels",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_CLIP"},
+							},
+							&cli.StringFlag{
+								Name: "time",
+								Value: chrome.TIMEWEB_TIME,
+								Usage: "how the time of the page is controlled: timeweb, which requires timeweb.js on the page, or the virtual time of Chrome",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_TIME"},
+							},
+							&cli.StringFlag{
+								Name: "ready",
+								Usage: "conditions to wait for before capturing, separated by semicolons: networkidle, fonts, js:EXPRESSION or selector:SELECTOR",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_READY"},
+							},
+							&cli.DurationFlag{
+								Name: "readyTimeout",
+								Value: chrome.DEFAULT_READY_TIMEOUT,
+								Usage: "how long each ready condition can take",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_READYTIMEOUT"},
+							},
+							&cli.BoolFlag{
+								Name: "readyEachFrame",
+								Usage: "also wait for the ready conditions before each frame",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_READYEACHFRAME"},
+							},
+							&cli.BoolFlag{
+								Name: "draft",
+								Usage: "record a quick preview: jpeg frames at half the resolution, encoded with the " + chrome.DRAFT_PRESET + " preset",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_DRAFT"},
+							},
+							&cli.StringFlag{
+								Name: "motionBlur",
+								Usage: "average samples sub-frames captured while the shutter is open, as samples,shutter, like 8,0.5",
+								EnvVars: []string{"OMEGA_CHROME_RECORD_MOTIONBLUR"},
+							},
 						},
 						Action: func(c *cli.Context) error {
 							// Create the recording params from the provided flags.
 							params := chrome.RecordParams{
 								Duration: c.Float64("duration"),
+								FPS     : c.Float64("fps"),
 								Workers : c.Int("workers"),
+								Retries : c.Int("retries"),
+								Output  : c.String("output"),
+								Preset  : c.String("preset"),
+								FramesDir: c.String("frames"),
+								Zip     : c.String("zip"),
+								NoVideo : c.Bool("noVideo"),
 								Width   : c.Int64("width"),
 								Height  : c.Int64("height"),
+								DeviceScaleFactor: c.Float64("deviceScaleFactor"),
+								OutputWidth : c.Int64("outputWidth"),
+								OutputHeight: c.Int64("outputHeight"),
+								Supersample: c.Int("supersample"),
+								Format  : c.String("format"),
+								Quality : c.Int64("quality"),
+								Selector: c.String("selector"),
+								Time    : c.String("time"),
+								Transparent: c.Bool("transparent"),
+								URL     : c.String("url"),
+								File    : c.String("file"),
+								Dir     : c.String("dir"),
+								EntryPoint: c.String("entryPoint"),
+								Protocol: c.Bool("omega"),
+								Draft   : c.Bool("draft"),
+								ReadyEachFrame: c.Bool("readyEachFrame"),
+							}
+							// Pass the extra ffmpeg arguments, which can be quoted
+							ffmpegArgs, err := chrome.ParseFFmpegArgs(c.String("ffmpegArgs"))
+							if err != nil {
+								return err
+							}
+							params.FFmpegArgs = ffmpegArgs
+							// Blur the frames
+							if blur := c.String("motionBlur"); blur != "" {
+								motionBlur, err := chrome.ParseMotionBlur(blur)
+								if err != nil {
+									return err
+								}
+								params.MotionBlur = motionBlur
+							}
+							// Choose the range of frames to record
+							last := -1
+							if c.IsSet("endFrame") {
+								last = c.Int("endFrame")
+							}
+							start, end, err := params.FrameRange(c.Int("startFrame"), last, c.String("from"), c.String("to"))
+							if err != nil {
+								return err
+							}
+							params.StartFrame, params.EndFrame = start, end
+							// Capture a region of the page
+							if clip := c.String("clip"); clip != "" {
+								region, err := chrome.ParseClip(clip)
+								if err != nil {
+									return err
+								}
+								params.Clip = region
+							}
+							// Wait for the page to be ready
+							ready, err := chrome.ParseReadyConditions(c.String("ready"), c.Duration("readyTimeout"))
+							if err != nil {
+								return err
+							}
+							params.Ready = ready
+							// Load the template data
+							if path := c.String("data"); path != "" {
+								data, err := chrome.LoadData(path)
+								if err != nil {
+									return err
+								}
+								params.Data = data
 							}
 							// Start recording
 							if err := chrome.Record(params); err != nil {
@@ -225,6 +546,233 @@ func main() {
 							return nil
 						},
 					},
+					{
+						Name: "still",
+						Usage: "capture a single frame of a Chrome animation as a PNG image",
+						UsageText: "omega chrome still [OPTIONS] OUTPUT",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "at",
+								Value: "0",
+								Usage: "time of the frame, like 2.5s, or ms",
+								EnvVars: []string{"OMEGA_CHROME_STILL_AT"},
+							},
+							&cli.Float64Flag{
+								Name: "duration",
+								Aliases: []string{"d"},
+								Value: 1000,
+								Usage: "duration of the animation passed to the page",
+								EnvVars: []string{"OMEGA_CHROME_STILL_DURATION"},
+							},
+							&cli.Float64Flag{
+								Name: "fps",
+								Value: chrome.DEFAULT_FPS,
+								Usage: "frame rate used to find the frame",
+								EnvVars: []string{"OMEGA_CHROME_STILL_FPS"},
+							},
+							&cli.StringFlag{
+								Name: "url",
+								Usage: "URL of the page to capture",
+								DefaultText: "the handler of the web server",
+								EnvVars: []string{"OMEGA_CHROME_STILL_URL"},
+							},
+							&cli.StringFlag{
+								Name: "file",
+								Usage: "local HTML file to capture, served with the files next to it",
+								EnvVars: []string{"OMEGA_CHROME_STILL_FILE"},
+							},
+							&cli.StringFlag{
+								Name: "dir",
+								Usage: "local directory to capture, served as static files from its index.html",
+								EnvVars: []string{"OMEGA_CHROME_STILL_DIR"},
+							},
+							&cli.StringFlag{
+								Name: "entryPoint",
+								Aliases: []string{"e"},
+								Usage: "entrypoint of an esbuild project to build and capture",
+								EnvVars: []string{"OMEGA_CHROME_STILL_ENTRYPOINT"},
+							},
+							&cli.StringFlag{
+								Name: "data",
+								Usage: "JSON or YAML file passed to the templates",
+								EnvVars: []string{"OMEGA_CHROME_STILL_DATA"},
+							},
+							&cli.BoolFlag{
+								Name: "transparent",
+								Usage: "capture over a transparent background",
+								EnvVars: []string{"OMEGA_CHROME_STILL_TRANSPARENT"},
+							},
+							&cli.Float64Flag{
+								Name: "width",
+								Aliases: []string{"W"},
+								Value: 1920,
+								Usage: "width of the still",
+								EnvVars: []string{"OMEGA_CHROME_STILL_WIDTH"},
+							},
+							&cli.Float64Flag{
+								Name: "height",
+								Aliases: []string{"H"},
+								Value: 1080,
+								Usage: "height of the still",
+								EnvVars: []string{"OMEGA_CHROME_STILL_HEIGHT"},
+							},
+							&cli.Float64Flag{
+								Name: "deviceScaleFactor",
+								Aliases: []string{"scale"},
+								Usage: "device pixels per CSS pixel, like 2 to capture a 1920x1080 layout in 4K",
+								DefaultText: "1, or the ratio of the output size to the viewport",
+								EnvVars: []string{"OMEGA_CHROME_STILL_DEVICESCALEFACTOR"},
+							},
+							&cli.Int64Flag{
+								Name: "outputWidth",
+								Usage: "width of the captured frames, keeping the layout of the viewport",
+								EnvVars: []string{"OMEGA_CHROME_STILL_OUTPUTWIDTH"},
+							},
+							&cli.Int64Flag{
+								Name: "outputHeight",
+								Usage: "height of the captured frames, keeping the layout of the viewport",
+								EnvVars: []string{"OMEGA_CHROME_STILL_OUTPUTHEIGHT"},
+							},
+							&cli.IntFlag{
+								Name: "supersample",
+								Usage: "capture the frames at N times their resolution and downscale them, smoothing lines and text",
+								EnvVars: []string{"OMEGA_CHROME_STILL_SUPERSAMPLE"},
+							},
+							&cli.StringFlag{
+								Name: "format",
+								Value: "png",
+								Usage: "format of the captured frames (png, jpeg, webp)",
+								EnvVars: []string{"OMEGA_CHROME_STILL_FORMAT"},
+							},
+							&cli.Int64Flag{
+								Name: "quality",
+								Usage: "quality of the jpeg and webp frames, from 0 to 100",
+								EnvVars: []string{"OMEGA_CHROME_STILL_QUALITY"},
+							},
+							&cli.StringFlag{
+								Name: "selector",
+								Usage: "capture only the element that matches the CSS selector",
+								EnvVars: []string{"OMEGA_CHROME_STILL_SELECTOR"},
+							},
+							&cli.StringFlag{
+								Name: "clip",
+								Usage: "capture only a region of the page, as x,y,width,height in CSS pixels",
+								EnvVars: []string{"OMEGA_CHROME_STILL_CLIP"},
+							},
+							&cli.StringFlag{
+								Name: "time",
+								Value: chrome.TIMEWEB_TIME,
+								Usage: "how the time of the page is controlled: timeweb, which requires timeweb.js on the page, or the virtual time of Chrome",
+								EnvVars: []string{"OMEGA_CHROME_STILL_TIME"},
+							},
+							&cli.StringFlag{
+								Name: "ready",
+								Usage: "conditions to wait for before capturing, separated by semicolons: networkidle, fonts, js:EXPRESSION or selector:SELECTOR",
+								EnvVars: []string{"OMEGA_CHROME_STILL_READY"},
+							},
+							&cli.DurationFlag{
+								Name: "readyTimeout",
+								Value: chrome.DEFAULT_READY_TIMEOUT,
+								Usage: "how long each ready condition can take",
+								EnvVars: []string{"OMEGA_CHROME_STILL_READYTIMEOUT"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							// Check if an output was supplied
+							if c.NArg() == 0 {
+								return errors.New("no output path was supplied")
+							}
+							at, err := chrome.ParseTime(c.String("at"))
+							if err != nil {
+								return err
+							}
+							params := chrome.RecordParams{
+								Duration: c.Float64("duration"),
+								FPS     : c.Float64("fps"),
+								Width   : c.Int64("width"),
+								Height  : c.Int64("height"),
+								DeviceScaleFactor: c.Float64("deviceScaleFactor"),
+								OutputWidth : c.Int64("outputWidth"),
+								OutputHeight: c.Int64("outputHeight"),
+								Supersample: c.Int("supersample"),
+								Format  : c.String("format"),
+								Quality : c.Int64("quality"),
+								Selector: c.String("selector"),
+								Time    : c.String("time"),
+								Transparent: c.Bool("transparent"),
+								URL     : c.String("url"),
+								File    : c.String("file"),
+								Dir     : c.String("dir"),
+								EntryPoint: c.String("entryPoint"),
+							}
+							// Capture a region of the page
+							if clip := c.String("clip"); clip != "" {
+								region, err := chrome.ParseClip(clip)
+								if err != nil {
+									return err
+								}
+								params.Clip = region
+							}
+							// Wait for the page to be ready
+							ready, err := chrome.ParseReadyConditions(c.String("ready"), c.Duration("readyTimeout"))
+							if err != nil {
+								return err
+							}
+							params.Ready = ready
+							// Load the template data
+							if path := c.String("data"); path != "" {
+								data, err := chrome.LoadData(path)
+								if err != nil {
+									return err
+								}
+								params.Data = data
+							}
+							return chrome.Still(params, at, c.Args().Get(0))
+						},
+					},
+					{
+						Name: "batch",
+						Usage: "record the variants of an animation listed on a manifest",
+						UsageText: "omega chrome batch [OPTIONS] MANIFEST",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name: "concurrency",
+								Aliases: []string{"c"},
+								Usage: "maximum number of variants recorded at once",
+								DefaultText: "the manifest concurrency",
+								EnvVars: []string{"OMEGA_CHROME_BATCH_CONCURRENCY"},
+							},
+							&cli.StringFlag{
+								Name: "report",
+								Aliases: []string{"r"},
+								Usage: "path of the summary report",
+								DefaultText: "the manifest report, or " + chrome.DEFAULT_BATCH_REPORT,
+								EnvVars: []string{"OMEGA_CHROME_BATCH_REPORT"},
+							},
+						},
+						Action: func(c *cli.Context) error {
+							// Check if a manifest was supplied
+							if c.NArg() == 0 {
+								return errors.New("no manifest was supplied")
+							}
+							manifest, err := chrome.LoadManifest(c.Args().Get(0))
+							if err != nil {
+								return err
+							}
+
+							// Overwrite the manifest options
+							if concurrency := c.Int("concurrency"); concurrency != 0 {
+								manifest.Concurrency = concurrency
+							}
+							if report := c.String("report"); report != "" {
+								manifest.Report = report
+							}
+
+							// Record every variant
+							_, err = chrome.Batch(manifest)
+							return err
+						},
+					},
 					{
 						Name: "serve",
 						Usage: "serve the handler web server",
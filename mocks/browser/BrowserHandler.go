@@ -5,7 +5,11 @@ package mocks
 import (
 	context "context"
 
+	browser "gux.codes/omega/pkg/browser"
+
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // BrowserHandler is an autogenerated mock type for the BrowserHandler type
@@ -13,6 +17,20 @@ type BrowserHandler struct {
 	mock.Mock
 }
 
+// AdvanceVirtualTime provides a mock function with given fields: ctx, budget
+func (_m *BrowserHandler) AdvanceVirtualTime(ctx context.Context, budget float64) error {
+	ret := _m.Called(ctx, budget)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, float64) error); ok {
+		r0 = rf(ctx, budget)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Evaluate provides a mock function with given fields: ctx, script
 func (_m *BrowserHandler) Evaluate(ctx context.Context, script string) ([]byte, error) {
 	ret := _m.Called(ctx, script)
@@ -36,13 +54,13 @@ func (_m *BrowserHandler) Evaluate(ctx context.Context, script string) ([]byte,
 	return r0, r1
 }
 
-// Navigate provides a mock function with given fields: ctx, urlstr, width, height
-func (_m *BrowserHandler) Navigate(ctx context.Context, urlstr string, width int64, height int64) error {
-	ret := _m.Called(ctx, urlstr, width, height)
+// Navigate provides a mock function with given fields: ctx, urlstr, viewport
+func (_m *BrowserHandler) Navigate(ctx context.Context, urlstr string, viewport browser.Viewport) error {
+	ret := _m.Called(ctx, urlstr, viewport)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64) error); ok {
-		r0 = rf(ctx, urlstr, width, height)
+	if rf, ok := ret.Get(0).(func(context.Context, string, browser.Viewport) error); ok {
+		r0 = rf(ctx, urlstr, viewport)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -75,13 +93,55 @@ func (_m *BrowserHandler) NewContext(parent context.Context) (context.Context, c
 	return r0, r1
 }
 
-// Screenshot provides a mock function with given fields: ctx
-func (_m *BrowserHandler) Screenshot(ctx context.Context) ([]byte, error) {
+// OnConsole provides a mock function with given fields: ctx, fn
+func (_m *BrowserHandler) OnConsole(ctx context.Context, fn func(string)) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(string)) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PauseVirtualTime provides a mock function with given fields: ctx
+func (_m *BrowserHandler) PauseVirtualTime(ctx context.Context) error {
 	ret := _m.Called(ctx)
 
-	var r0 []byte
-	if rf, ok := ret.Get(0).(func(context.Context) []byte); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
 		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reset provides a mock function with given fields: ctx
+func (_m *BrowserHandler) Reset(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Screenshot provides a mock function with given fields: ctx, options
+func (_m *BrowserHandler) Screenshot(ctx context.Context, options browser.ScreenshotOptions) ([]byte, error) {
+	ret := _m.Called(ctx, options)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, browser.ScreenshotOptions) []byte); ok {
+		r0 = rf(ctx, options)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
@@ -89,11 +149,25 @@ func (_m *BrowserHandler) Screenshot(ctx context.Context) ([]byte, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, browser.ScreenshotOptions) error); ok {
+		r1 = rf(ctx, options)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+
+// WaitNetworkIdle provides a mock function with given fields: ctx, quiet
+func (_m *BrowserHandler) WaitNetworkIdle(ctx context.Context, quiet time.Duration) error {
+	ret := _m.Called(ctx, quiet)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) error); ok {
+		r0 = rf(ctx, quiet)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -2,22 +2,90 @@ package browser
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
 )
 
+// Viewport specifies how a page is displayed.
+type Viewport struct {
+	// Width of the viewport in pixels.
+	Width int64
+	// Height of the viewport in pixels.
+	Height int64
+	// Transparent replaces the default white background of the page with a
+	// transparent one, so screenshots keep their alpha channel.
+	Transparent bool
+	// DeviceScaleFactor is the number of device pixels per CSS pixel.
+	// Screenshots measure Width * DeviceScaleFactor by Height *
+	// DeviceScaleFactor pixels. Defaults to 1.
+	DeviceScaleFactor float64
+}
+
+// Scale returns the device scale factor of the viewport.
+func (v Viewport) Scale() float64 {
+	if v.DeviceScaleFactor <= 0 {
+		return 1
+	}
+	return v.DeviceScaleFactor
+}
+
+// Image formats of the screenshots.
+const (
+	FormatPNG string = "png"
+	FormatJPEG string = "jpeg"
+	FormatWebP string = "webp"
+)
+
+// Clip is a region of the page in CSS pixels.
+type Clip struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Width float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ScreenshotOptions specifies how a screenshot is encoded.
+type ScreenshotOptions struct {
+	// Format of the image: FormatPNG, FormatJPEG or FormatWebP. Defaults to
+	// FormatPNG.
+	Format string
+	// Quality of the JPEG and WebP images, from 0 to 100.
+	Quality int64
+	// Clip captures a region of the page instead of the whole viewport.
+	Clip *Clip
+}
+
 // browser abstracts the communication and handling of a browser instance.
 type BrowserHandler interface {
 	// NewContext opens a new browser instance if none were defined on the parent context.
 	NewContext(parent context.Context) (context.Context, context.CancelFunc)
 	// Evaluate evaluates any JavaScript script on the current context, and returns its output.
 	Evaluate(ctx context.Context, script string) ([]byte, error)
-	// Navigate navigates the current context to the provided url
-	Navigate(ctx context.Context, urlstr string, width, height int64) error
-	// Screenshot takes a screensho of the context's viewport, trimmed according to the provided params.
-	Screenshot(ctx context.Context) ([]byte, error)
+	// Navigate navigates the current context to the provided url, displayed on the viewport.
+	Navigate(ctx context.Context, urlstr string, viewport Viewport) error
+	// Screenshot takes a screenshot of the context's viewport, encoded according to the provided options.
+	Screenshot(ctx context.Context, options ScreenshotOptions) ([]byte, error)
+	// OnConsole calls fn with the text of every console message logged by the page of the context,
+	// until the context is done.
+	OnConsole(ctx context.Context, fn func(message string)) error
+	// PauseVirtualTime replaces the clock of the context with a virtual one, paused at 0.
+	PauseVirtualTime(ctx context.Context) error
+	// AdvanceVirtualTime runs the virtual clock of the context for budget ms, and waits until it
+	// pauses again.
+	AdvanceVirtualTime(ctx context.Context, budget float64) error
+	// WaitNetworkIdle waits until the page of the context has no pending request for quiet.
+	WaitNetworkIdle(ctx context.Context, quiet time.Duration) error
+	// Reset clears the page and the emulation overrides of the context, so it can be reused.
+	Reset(ctx context.Context) error
 }
 
 // ChromeBrowser is an implementation of the browserHandler interface to interact with a Chrome
@@ -36,22 +104,211 @@ func (ChromeBrowser) Evaluate(ctx context.Context, script string) ([]byte, error
 }
 
 // Navigate navigates the current browser to the provided url.
-func (ChromeBrowser) Navigate(ctx context.Context, urlstr string, width, height int64) error {
-	return chromedp.Run(ctx, chromedp.Tasks{
-		emulation.SetDeviceMetricsOverride(width, height, 1, false).
+func (ChromeBrowser) Navigate(ctx context.Context, urlstr string, viewport Viewport) error {
+	tasks := chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(viewport.Width, viewport.Height, viewport.Scale(), false).
 		WithScreenOrientation(&emulation.ScreenOrientation{
 			Type: emulation.OrientationTypePortraitPrimary,
 			Angle: 0,
 		}),
-		chromedp.Navigate(urlstr),
-	})
+	}
+	// Chrome paints a white background behind the page unless it is overridden
+	if viewport.Transparent {
+		tasks = append(tasks, emulation.SetDefaultBackgroundColorOverride().
+			WithColor(&cdp.RGBA{R: 0, G: 0, B: 0, A: 0}))
+	}
+	// Track the requests of the page from the start, for WaitNetworkIdle
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+	trackNetwork(ctx)
+	return chromedp.Run(ctx, append(tasks, chromedp.Navigate(urlstr)))
+}
+
+// Reset navigates the page to about:blank and clears the viewport and background overrides set by
+// Navigate. The globals of the previous page go away with it.
+func (ChromeBrowser) Reset(ctx context.Context) error {
+	return chromedp.Run(ctx,
+		emulation.ClearDeviceMetricsOverride(),
+		emulation.SetDefaultBackgroundColorOverride(),
+		chromedp.Navigate("about:blank"),
+	)
 }
 
 // Screenshot takes a screenshot of what is being shown on the current browser's viewport
 // cropped according to the provided coordinates.
-func (ChromeBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+func (ChromeBrowser) Screenshot(ctx context.Context, options ScreenshotOptions) ([]byte, error) {
 	var buf []byte
-	return buf, chromedp.Run(ctx, screenshot(&buf))
+	return buf, chromedp.Run(ctx, screenshot(&buf, options))
+}
+
+// OnConsole listens to the console messages of the page, until ctx is done. The arguments of each
+// message are joined by spaces, like the console displays them.
+func (ChromeBrowser) OnConsole(ctx context.Context, fn func(message string)) error {
+	// The target must exist before listening to its events
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*runtime.EventConsoleAPICalled)
+		if !ok {
+			return
+		}
+		args := make([]string, 0, len(e.Args))
+		for _, arg := range e.Args {
+			var text string
+			if err := json.Unmarshal(arg.Value, &text); err == nil {
+				args = append(args, text)
+			} else if len(arg.Value) > 0 {
+				args = append(args, string(arg.Value))
+			} else {
+				args = append(args, arg.Description)
+			}
+		}
+		fn(strings.Join(args, " "))
+	})
+	return nil
+}
+
+// PauseVirtualTime pauses the virtual time of the page. Timers, animation frames, CSS animations
+// and videos only move forward when the virtual time is advanced.
+func (ChromeBrowser) PauseVirtualTime(ctx context.Context) error {
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := emulation.SetVirtualTimePolicy(emulation.VirtualTimePolicyPause).Do(ctx)
+		return err
+	}))
+}
+
+// AdvanceVirtualTime lets the virtual time of the page run for budget ms. The virtual time waits
+// for the pending network fetches, so resources load before the time moves.
+func (ChromeBrowser) AdvanceVirtualTime(ctx context.Context, budget float64) error {
+	// Listen to the end of the budget before asking for it
+	expired := make(chan struct{}, 1)
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chromedp.ListenTarget(lctx, func(ev interface{}) {
+		if _, ok := ev.(*emulation.EventVirtualTimeBudgetExpired); ok {
+			select {
+			case expired <- struct{}{}:
+			default:
+			}
+		}
+	})
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := emulation.SetVirtualTimePolicy(emulation.VirtualTimePolicyPauseIfNetworkFetchesPending).
+			WithBudget(budget).
+			Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return err
+	}
+	select {
+	case <- expired:
+		return nil
+	case <- ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// networkTracker counts the pending requests of a page since it started loading.
+type networkTracker struct {
+	mu sync.Mutex
+	pending map[network.RequestID]bool
+	// last is the time of the last request event.
+	last time.Time
+	cancel context.CancelFunc
+}
+
+// trackers holds the network tracker of each target, replaced on every navigation.
+var trackers = struct {
+	sync.Mutex
+	targets map[*chromedp.Target]*networkTracker
+}{targets: make(map[*chromedp.Target]*networkTracker)}
+
+// trackNetwork starts counting the requests of the page of the context, replacing the tracker of
+// the previous page. The target of the context must exist.
+func trackNetwork(ctx context.Context) *networkTracker {
+	target := chromedp.FromContext(ctx).Target
+	lctx, cancel := context.WithCancel(ctx)
+	tracker := &networkTracker{pending: make(map[network.RequestID]bool), last: time.Now(), cancel: cancel}
+	chromedp.ListenTarget(lctx, tracker.listen)
+
+	trackers.Lock()
+	if previous := trackers.targets[target]; previous != nil {
+		previous.cancel()
+	}
+	trackers.targets[target] = tracker
+	trackers.Unlock()
+	// Forget the tracker once it is replaced or the context ends
+	go func() {
+		<- lctx.Done()
+		trackers.Lock()
+		defer trackers.Unlock()
+		if trackers.targets[target] == tracker {
+			delete(trackers.targets, target)
+		}
+	}()
+	return tracker
+}
+
+// listen updates the pending requests with the network events of the page.
+func (t *networkTracker) listen(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.pending[e.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(t.pending, e.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.pending, e.RequestID)
+	default:
+		return
+	}
+	t.last = time.Now()
+}
+
+// wait returns how long the page has to stay quiet to be idle, or 0 if it is idle.
+func (t *networkTracker) wait(quiet time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) > 0 {
+		return quiet
+	}
+	if wait := quiet - time.Since(t.last); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// WaitNetworkIdle waits until no request of the page is pending for the quiet duration. The
+// requests are tracked since Navigate loaded the page. On pages loaded otherwise, only the requests
+// sent after the call are seen.
+func (ChromeBrowser) WaitNetworkIdle(ctx context.Context, quiet time.Duration) error {
+	// The target must exist before listening to its events
+	if err := chromedp.Run(ctx); err != nil {
+		return err
+	}
+	trackers.Lock()
+	tracker := trackers.targets[chromedp.FromContext(ctx).Target]
+	trackers.Unlock()
+	if tracker == nil {
+		tracker = trackNetwork(ctx)
+	}
+
+	// Check again every time the quiet duration could have passed
+	for {
+		wait := tracker.wait(quiet)
+		if wait == 0 {
+			return nil
+		}
+		select {
+		case <- time.After(wait):
+		case <- ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // Chrome is a package-level variable of type BrowserHandle to hold a reference to ChromeBrowser
@@ -63,12 +320,28 @@ func init() {
 }
 
 // Screenshot Action Task
-func screenshot(buf *[]byte) chromedp.Tasks {
+func screenshot(buf *[]byte, options ScreenshotOptions) chromedp.Tasks {
 	return chromedp.Tasks{chromedp.ActionFunc(func(ctx context.Context) error {
 		var err error
-		*buf, err = page.CaptureScreenshot().
-			WithFormat(page.CaptureScreenshotFormatPng).
-			Do(ctx)
+		params := page.CaptureScreenshot()
+		switch options.Format {
+		case "", FormatPNG:
+			params = params.WithFormat(page.CaptureScreenshotFormatPng)
+		default:
+			// WebP isn't listed by cdproto, but Chrome accepts it
+			params = params.WithFormat(page.CaptureScreenshotFormat(options.Format)).
+				WithQuality(options.Quality)
+		}
+		if options.Clip != nil {
+			params = params.WithClip(&page.Viewport{
+				X: options.Clip.X,
+				Y: options.Clip.Y,
+				Width: options.Clip.Width,
+				Height: options.Clip.Height,
+				Scale: 1,
+			})
+		}
+		*buf, err = params.Do(ctx)
 		return err
 	})}
 }
\ No newline at end of file
@@ -0,0 +1,92 @@
+package chrome
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"sync"
+
+	"gux.codes/omega/pkg/utils"
+)
+
+// ALPHA_CHECK_FRAMES is the number of frames of a transparent recording
+// checked for a transparent pixel.
+const ALPHA_CHECK_FRAMES int = 30
+
+// HasAlpha reports whether a PNG image has at least one pixel that isn't fully
+// opaque.
+func HasAlpha(data []byte) (bool, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	// Most image types know if they are opaque
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque(), nil
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a < 0xffff {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// alphaWriter is a FrameSink that warns if none of the first
+// ALPHA_CHECK_FRAMES frames of a transparent recording has a transparent
+// pixel, which usually means the page paints its own background. Animations
+// can start from an opaque frame, so the recording goes on. Frames that
+// aren't PNG images aren't checked.
+type alphaWriter struct {
+	FrameSink
+	mu sync.Mutex
+	// checked counts the frames checked without a transparent pixel.
+	checked int
+	// transparent is true once a frame has a transparent pixel.
+	transparent bool
+	warned bool
+}
+
+// WriteFrame checks the frame, until a transparent one is found, and writes
+// it.
+func (w *alphaWriter) WriteFrame(index int, data []byte) error {
+	w.mu.Lock()
+	if !w.transparent && w.checked < ALPHA_CHECK_FRAMES {
+		if ok, err := HasAlpha(data); err == nil {
+			w.transparent = ok
+			if !ok {
+				w.checked++
+			}
+		}
+		if w.checked == ALPHA_CHECK_FRAMES {
+			w.warn()
+		}
+	}
+	w.mu.Unlock()
+	return w.FrameSink.WriteFrame(index, data)
+}
+
+// warn prints, once, that the checked frames have no transparent pixel.
+func (w *alphaWriter) warn() {
+	if !w.warned && !w.transparent && w.checked > 0 {
+		w.warned = true
+		utils.Info(fmt.Sprintf("The first %d frames have no transparent pixel, check the background of the page", w.checked))
+	}
+}
+
+// Close warns if none of the frames was transparent, and closes the
+// underlying FrameSink.
+func (w *alphaWriter) Close() error {
+	w.mu.Lock()
+	w.warn()
+	w.mu.Unlock()
+	return w.FrameSink.Close()
+}
+
+// Abort aborts the underlying FrameSink, if it can be aborted.
+func (w *alphaWriter) Abort(err error) {
+	abort(w.FrameSink, err)
+}
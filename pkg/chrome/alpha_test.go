@@ -0,0 +1,140 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type AlphaSuite struct {
+	suite.Suite
+}
+
+func (suite *AlphaSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+// encode returns a PNG image filled with the color, like a screenshot of an
+// empty page.
+func (suite *AlphaSuite) encode(c color.Color) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	suite.NoError(png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func (suite *AlphaSuite) TestHasAlpha() {
+	transparent, err := HasAlpha(suite.encode(color.NRGBA{0, 0, 0, 0}))
+	suite.NoError(err)
+	suite.True(transparent)
+
+	opaque, err := HasAlpha(suite.encode(color.NRGBA{255, 255, 255, 255}))
+	suite.NoError(err)
+	suite.False(opaque)
+
+	_, err = HasAlpha([]byte("not a png"))
+	suite.Error(err)
+}
+
+func (suite *AlphaSuite) TestTransparentPreset() {
+	// Presets that drop the alpha channel should be replaced
+	preset, err := RecordParams{Preset: "h264", Transparent: true}.preset()
+	suite.NoError(err)
+	suite.Equal(ALPHA_PRESET, preset.Name)
+	params := RecordParams{Transparent: true}
+	args := preset.FFmpegArgs(60, params.frameFormat(), "", nil)
+	suite.Contains(args, "yuva444p10le")
+	suite.Equal([]string{"-c:v", "png"}, args[3:5])
+
+	// Alpha presets should be kept
+	preset, err = RecordParams{Preset: "vp9-alpha", Transparent: true}.preset()
+	suite.NoError(err)
+	suite.Equal("vp9-alpha", preset.Name)
+	suite.Contains(preset.FFmpegArgs(60, params.frameFormat(), "", nil), "yuva420p")
+
+	// Opaque recordings should keep their preset
+	preset, err = RecordParams{Preset: "h264"}.preset()
+	suite.NoError(err)
+	suite.Equal("h264", preset.Name)
+}
+
+func (suite *AlphaSuite) TestTransparentRecord() {
+	params := RecordParams{
+		Duration: 100,
+		URL     : "https://example.com",
+		Workers : 2,
+		Width   : 4,
+		Height  : 4,
+		Transparent: true,
+	}
+
+	suite.Run("should record transparent frames", func() {
+		handler := mockBrowser(&mbrowser.BrowserHandler{}, suite.encode(color.NRGBA{255, 0, 0, 128}))
+		frames := &mapFrameSink{frames: make(map[int][]byte)}
+		params.Sink = params.process(frames)
+		suite.IsType(&alphaWriter{}, params.Sink)
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+		// The browser should be asked for a transparent background
+		handler.AssertCalled(suite.T(), "Navigate", mock.Anything, params.URL, browser.Viewport{Width: 4, Height: 4, Transparent: true})
+		suite.Len(frames.frames, params.frames())
+	})
+
+	suite.Run("should warn if the page paints its background", func() {
+		mockBrowser(&mbrowser.BrowserHandler{}, suite.encode(color.NRGBA{255, 255, 255, 255}))
+		frames := &mapFrameSink{frames: make(map[int][]byte)}
+		params.Sink = params.process(frames)
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Len(frames.frames, params.frames())
+		suite.NoError(params.Sink.Close())
+		suite.True(params.Sink.(*alphaWriter).warned)
+	})
+
+	suite.Run("should only check the first frames", func() {
+		opaque, transparent := suite.encode(color.NRGBA{255, 255, 255, 255}), suite.encode(color.NRGBA{0, 0, 0, 0})
+		w := &alphaWriter{FrameSink: &mapFrameSink{frames: make(map[int][]byte)}}
+		// An animation that starts from an opaque frame shouldn't warn
+		suite.NoError(w.WriteFrame(0, opaque))
+		suite.NoError(w.WriteFrame(1, transparent))
+		suite.NoError(w.Close())
+		suite.False(w.warned)
+
+		w = &alphaWriter{FrameSink: &mapFrameSink{frames: make(map[int][]byte)}}
+		for i := 0; i < ALPHA_CHECK_FRAMES + 5; i++ {
+			suite.NoError(w.WriteFrame(i, opaque))
+		}
+		suite.True(w.warned)
+		suite.Equal(ALPHA_CHECK_FRAMES, w.checked)
+	})
+}
+
+func (suite *AlphaSuite) TestAlphaPreset() {
+	for _, name := range PresetNames() {
+		if name == "h264" || name == "h264-fast" || name == "gif" {
+			suite.False(Presets[name].Alpha, name)
+		} else {
+			suite.True(Presets[name].Alpha, name)
+		}
+	}
+	suite.True(Presets[ALPHA_PRESET].Alpha)
+	suite.Contains(Presets[ALPHA_PRESET].Args, "yuva444p10le")
+}
+
+// Run the test suite
+func TestAlphaSuite(t *testing.T) {
+	suite.Run(t, new(AlphaSuite))
+}
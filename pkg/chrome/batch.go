@@ -0,0 +1,339 @@
+package chrome
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"gux.codes/omega/pkg/utils"
+)
+
+// DEFAULT_BATCH_REPORT is the path of the batch report when the manifest
+// doesn't set one.
+const DEFAULT_BATCH_REPORT string = "./batch-report.yml"
+
+// Manifest describes a batch of variants of the same animation.
+type Manifest struct {
+	// Page to record, like the --url, --file, --dir and --entryPoint flags.
+	// Defaults to the handler of the web server. Only the handler and the
+	// entry point are rendered with the params of each variant.
+	URL string `yaml:"url,omitempty"`
+	File string `yaml:"file,omitempty"`
+	Dir string `yaml:"dir,omitempty"`
+	EntryPoint string `yaml:"entryPoint,omitempty"`
+	// IgnoreOmega records the whole duration of each variant, ignoring the
+	// Omega.js commands of the page.
+	IgnoreOmega bool `yaml:"ignoreOmega,omitempty"`
+	// Concurrency is the maximum number of variants recorded at once.
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Report is the path of the summary report.
+	Report string `yaml:"report,omitempty"`
+	// Defaults holds the settings shared by every variant.
+	Defaults Variant `yaml:"defaults,omitempty"`
+	Variants []Variant `yaml:"variants"`
+}
+
+// Variant is a recording of a batch. Its zero fields take the value of the
+// manifest defaults.
+type Variant struct {
+	// Name identifies the variant on the report and the web server.
+	Name string `yaml:"name,omitempty"`
+	// Output defaults to {{ name }}.{{ extension }}.
+	Output string `yaml:"output,omitempty"`
+	Preset string `yaml:"preset,omitempty"`
+	Width int64 `yaml:"width,omitempty"`
+	Height int64 `yaml:"height,omitempty"`
+	Duration float64 `yaml:"duration,omitempty"`
+	FPS float64 `yaml:"fps,omitempty"`
+	Workers int `yaml:"workers,omitempty"`
+	// Data is passed to the templates. Its keys are merged with the ones of
+	// the default data.
+	Data interface{} `yaml:"data,omitempty"`
+}
+
+// VariantResult holds the outcome of a variant.
+type VariantResult struct {
+	Name string `yaml:"name"`
+	Output string `yaml:"output"`
+	Succeeded bool `yaml:"succeeded"`
+	Error string `yaml:"error,omitempty"`
+	Frames int `yaml:"frames"`
+	Elapsed time.Duration `yaml:"elapsed"`
+}
+
+// BatchReport summarizes a batch.
+type BatchReport struct {
+	Succeeded int `yaml:"succeeded"`
+	Failed int `yaml:"failed"`
+	Variants []VariantResult `yaml:"variants"`
+}
+
+// LoadManifest reads a batch manifest from a YAML file.
+func LoadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, manifest.validate()
+}
+
+// validate checks that every variant has a unique name, and that data is
+// only set for pages rendered from the templates.
+func (m Manifest) validate() error {
+	if len(m.Variants) == 0 {
+		return errors.New("the manifest has no variants")
+	}
+	if m.Defaults.Data != nil && !m.templated() {
+		return errors.New("data is only passed to the handler and entryPoint pages, not to url, file or dir pages")
+	}
+	names := make(map[string]bool)
+	for i, variant := range m.Variants {
+		if variant.Name == "" {
+			return fmt.Errorf("variant %d has no name", i)
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("variant %s is defined twice", variant.Name)
+		}
+		if variant.Data != nil && !m.templated() {
+			return fmt.Errorf("variant %s has data, which is only passed to the handler and entryPoint pages", variant.Name)
+		}
+		names[variant.Name] = true
+	}
+	return nil
+}
+
+// templated reports whether the page is rendered by the web server from the
+// templates, which receive the params of each variant.
+func (m Manifest) templated() bool {
+	return m.URL == "" && m.File == "" && m.Dir == ""
+}
+
+// params returns the RecordParams of a variant, filling its zero fields with
+// the manifest defaults.
+func (m Manifest) params(variant Variant) RecordParams {
+	defaults := m.Defaults
+	params := RecordParams{
+		Preset: variant.Preset,
+		Width: variant.Width,
+		Height: variant.Height,
+		Duration: variant.Duration,
+		FPS: variant.FPS,
+		Workers: variant.Workers,
+		Data: mergeData(defaults.Data, variant.Data),
+		Retries: DEFAULT_RETRIES,
+		Protocol: !m.IgnoreOmega,
+	}
+	if params.Preset == "" {
+		params.Preset = defaults.Preset
+	}
+	if params.Width == 0 {
+		params.Width = defaults.Width
+	}
+	if params.Height == 0 {
+		params.Height = defaults.Height
+	}
+	if params.Duration == 0 {
+		params.Duration = defaults.Duration
+	}
+	if params.FPS == 0 {
+		params.FPS = defaults.FPS
+	}
+	if params.Workers == 0 {
+		params.Workers = defaults.Workers
+	}
+	// Use the same defaults as `omega chrome record`, which also follows the
+	// Omega.js commands and retries failed frames
+	if params.Width == 0 {
+		params.Width = 1920
+	}
+	if params.Height == 0 {
+		params.Height = 1080
+	}
+	if params.Duration == 0 {
+		params.Duration = 1000
+	}
+
+	// Every variant needs its own output
+	params.Output = variant.Output
+	if params.Output == "" {
+		if preset, err := PresetByName(params.Preset); err == nil && !preset.Sequence {
+			params.Output = variant.Name + "." + preset.Extension
+		} else {
+			params.Output = variant.Name
+		}
+	}
+	return params
+}
+
+// mergeData merges the keys of two data maps. Otherwise, the variant data
+// replaces the default one.
+func mergeData(defaults, data interface{}) interface{} {
+	base, ok := defaults.(map[string]interface{})
+	override, isMap := data.(map[string]interface{})
+	if !ok || (data != nil && !isMap) {
+		if data == nil {
+			return defaults
+		}
+		return data
+	}
+	merged := make(map[string]interface{}, len(base) + len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
+
+// variantURL adds the variant name to the query of the page URL, so the web
+// server renders the templates with the variant params.
+func variantURL(page string, name string) (string, error) {
+	u, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("variant", name)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// renderVariant records and encodes a variant. It is replaced on tests.
+var renderVariant = render
+
+// Batch records every variant of the manifest with a single web server and a
+// shared pool of browser contexts, and writes the summary report. It fails
+// if any variant failed.
+func Batch(manifest Manifest) (BatchReport, error) {
+	var report BatchReport
+	if err := manifest.validate(); err != nil {
+		return report, err
+	}
+
+	// Create a context canceled by Ctrl+C
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	// Serve the params of every variant from a single web server
+	webServerOptions := NewWebServerOptions()
+	source := RecordParams{URL: manifest.URL, File: manifest.File, Dir: manifest.Dir, EntryPoint: manifest.EntryPoint}
+	pageURL, root, err := source.page(webServerOptions.Port)
+	if err != nil {
+		return report, err
+	}
+	webServerOptions.Root = root
+	webServerOptions.Variants = make(map[string]gin.H)
+	for _, variant := range manifest.Variants {
+		webServerOptions.Variants[variant.Name] = manifest.params(variant).templateParams()
+	}
+	if manifest.EntryPoint != "" {
+		utils.Info("Building " + manifest.EntryPoint + "...")
+		if err := buildProject(manifest.EntryPoint); err != nil {
+			return report, err
+		}
+	}
+	go Serve(webServerOptions)
+
+	// Share the browser contexts between variants
+	pool := NewContextPool(ctx)
+	defer pool.Close()
+
+	report = batch(ctx, manifest, pageURL, pool)
+
+	// Write the summary report
+	path := manifest.Report
+	if path == "" {
+		path = DEFAULT_BATCH_REPORT
+	}
+	content, err := yaml.Marshal(report)
+	if err != nil {
+		return report, err
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return report, err
+	}
+	utils.Info(fmt.Sprintf("%d variants succeeded, %d failed. Report saved at %s", report.Succeeded, report.Failed, path))
+
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%d of %d variants failed", report.Failed, len(report.Variants))
+	}
+	return report, nil
+}
+
+// recordVariant records a variant with the browser contexts of the pool.
+func recordVariant(ctx context.Context, manifest Manifest, variant Variant, pageURL string, pool *ContextPool) VariantResult {
+	start := time.Now()
+	params := manifest.params(variant)
+	params.Pool = pool
+	result := VariantResult{Name: variant.Name, Output: params.Output}
+
+	var stats RecordStats
+	err := ctx.Err()
+	params.URL = pageURL
+	if err == nil && manifest.templated() {
+		params.URL, err = variantURL(pageURL, variant.Name)
+	}
+	if err == nil {
+		stats, result.Output, err = renderVariant(ctx, params)
+	}
+
+	result.Frames = stats.Frames
+	result.Elapsed = time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Succeeded = true
+	}
+	return result
+}
+
+// batch records the variants, up to manifest.Concurrency at once, and
+// returns their results in the manifest order.
+func batch(ctx context.Context, manifest Manifest, pageURL string, pool *ContextPool) BatchReport {
+	concurrency := manifest.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	results := make([]VariantResult, len(manifest.Variants))
+	for i, variant := range manifest.Variants {
+		wg.Add(1)
+		go func(i int, variant Variant) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <- semaphore }()
+
+			result := recordVariant(ctx, manifest, variant, pageURL, pool)
+			if result.Succeeded {
+				utils.Success(fmt.Sprintf("%s saved at %s", variant.Name, result.Output))
+			} else {
+				utils.Error(fmt.Sprintf("%s: %s", variant.Name, result.Error))
+			}
+			results[i] = result
+		}(i, variant)
+	}
+	wg.Wait()
+
+	report := BatchReport{Variants: results}
+	for _, result := range results {
+		if result.Succeeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
@@ -0,0 +1,202 @@
+package chrome
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	mutils "gux.codes/omega/mocks/utils"
+	"gux.codes/omega/pkg/browser"
+)
+
+type BatchSuite struct {
+	suite.Suite
+}
+
+func (suite *BatchSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+	renderVariant = render
+}
+
+const batchManifest = `
+concurrency: 2
+defaults:
+  duration: 2000
+  fps: 30
+  preset: prores4444
+  data:
+    color: red
+    language: en
+variants:
+  - name: acme-en
+    data:
+      customer: ACME
+  - name: acme-es
+    output: ./es/acme.webm
+    preset: vp9-alpha
+    width: 1280
+    height: 720
+    data:
+      customer: ACME
+      language: es
+`
+
+func (suite *BatchSuite) TestLoadManifest() {
+	dir := suite.T().TempDir()
+
+	suite.Run("should merge the defaults of every variant", func() {
+		path := filepath.Join(dir, "manifest.yml")
+		suite.NoError(ioutil.WriteFile(path, []byte(batchManifest), 0644))
+		m, err := LoadManifest(path)
+		suite.NoError(err)
+		suite.Equal(2, m.Concurrency)
+
+		en := m.params(m.Variants[0])
+		suite.Equal("acme-en.mov", en.Output)
+		suite.Equal("prores4444", en.Preset)
+		suite.Equal(int64(1920), en.Width)
+		suite.Equal(2000.0, en.Duration)
+		suite.Equal(30.0, en.FPS)
+		suite.Equal(map[string]interface{}{"color": "red", "language": "en", "customer": "ACME"}, en.Data)
+		// The other params should match the defaults of omega chrome record
+		suite.Equal(DEFAULT_RETRIES, en.Retries)
+		suite.True(en.Protocol)
+
+		es := m.params(m.Variants[1])
+		suite.Equal("./es/acme.webm", es.Output)
+		suite.Equal("vp9-alpha", es.Preset)
+		suite.Equal(int64(720), es.Height)
+		suite.Equal(map[string]interface{}{"color": "red", "language": "es", "customer": "ACME"}, es.Data)
+	})
+
+	suite.Run("should reject variants without a unique name", func() {
+		path := filepath.Join(dir, "invalid.yml")
+		suite.NoError(ioutil.WriteFile(path, []byte("variants:\n  - name: a\n  - name: a\n"), 0644))
+		_, err := LoadManifest(path)
+		suite.Error(err)
+	})
+
+	suite.Run("should reject data for pages that aren't templated", func() {
+		path := filepath.Join(dir, "url.yml")
+		suite.NoError(ioutil.WriteFile(path, []byte("url: https://example.com\nvariants:\n  - name: a\n    data:\n      customer: ACME\n"), 0644))
+		_, err := LoadManifest(path)
+		suite.EqualError(err, "variant a has data, which is only passed to the handler and entryPoint pages")
+	})
+}
+
+func (suite *BatchSuite) TestVariantURL() {
+	url, err := variantURL("http://localhost:38080/handler", "acme es")
+	suite.NoError(err)
+	suite.Equal("http://localhost:38080/handler?variant=acme+es", url)
+
+	url, err = variantURL("http://localhost:38080/dev?timeweb=true", "acme")
+	suite.NoError(err)
+	suite.Equal("http://localhost:38080/dev?timeweb=true&variant=acme", url)
+}
+
+func (suite *BatchSuite) TestBatch() {
+	m := Manifest{
+		Concurrency: 2,
+		Variants: []Variant{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}},
+	}
+
+	// Fake the rendering, failing variant c
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	urls := make(map[string]string)
+	renderVariant = func(ctx context.Context, params RecordParams) (RecordStats, string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		urls[params.Output] = params.URL
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if params.Output == "c.mp4" {
+			return RecordStats{}, params.Output, errors.New("ffmpeg failed")
+		}
+		return RecordStats{Frames: params.frames()}, params.Output, nil
+	}
+
+	report := batch(context.Background(), m, "http://localhost:38080/handler", nil)
+
+	suite.Equal(2, maxRunning)
+	suite.Equal(3, report.Succeeded)
+	suite.Equal(1, report.Failed)
+	suite.Equal("http://localhost:38080/handler?variant=b", urls["b.mp4"])
+	suite.Equal("a", report.Variants[0].Name)
+	suite.Equal(60, report.Variants[0].Frames)
+	suite.False(report.Variants[2].Succeeded)
+	suite.Equal("ffmpeg failed", report.Variants[2].Error)
+
+	// External pages should be recorded as they are
+	m.URL = "https://example.com/animation?lang=en"
+	batch(context.Background(), m, m.URL, nil)
+	suite.Equal(m.URL, urls["b.mp4"])
+}
+
+func (suite *BatchSuite) TestContextPool() {
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, nil)
+	writer := &mutils.Writer{}
+	writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+	// Consecutive recordings should reuse the browser contexts
+	pool := NewContextPool(context.Background())
+	defer pool.Close()
+	params := RecordParams{Duration: 100, Sink: NewDirectorySink("/tmp", writer), Workers: 3, Pool: pool}
+	for i := 0; i < 3; i++ {
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+	}
+	suite.Equal(3, pool.Created())
+	handler.AssertNumberOfCalls(suite.T(), "NewContext", 3)
+	handler.AssertNumberOfCalls(suite.T(), "Navigate", 9)
+	handler.AssertNumberOfCalls(suite.T(), "Reset", 9)
+}
+
+func (suite *BatchSuite) TestContextPoolVariants() {
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, []byte("frame"))
+	pool := NewContextPool(context.Background())
+	defer pool.Close()
+	variant := func(url string, time string) {
+		params := RecordParams{
+			Duration: 100,
+			URL     : url,
+			Time    : time,
+			Workers : 1,
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+			Pool    : pool,
+		}
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+	}
+
+	// The second variant should get the context of the first one, reset
+	variant("http://localhost/variant/a", TIMEWEB_TIME)
+	handler.AssertNumberOfCalls(suite.T(), "Reset", 1)
+	variant("http://localhost/variant/b", TIMEWEB_TIME)
+	suite.Equal(1, pool.Created())
+	handler.AssertNumberOfCalls(suite.T(), "Reset", 2)
+
+	// Contexts whose virtual time was paused shouldn't be reused
+	variant("http://localhost/variant/c", VIRTUAL_TIME)
+	variant("http://localhost/variant/d", TIMEWEB_TIME)
+	suite.Equal(2, pool.Created())
+	handler.AssertNumberOfCalls(suite.T(), "Reset", 3)
+}
+
+// Run the test suite
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
@@ -0,0 +1,109 @@
+package chrome
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// DEFAULT_SHUTTER is the fraction of the frame interval the shutter stays
+// open when the motion blur doesn't set one, like a 180° shutter.
+const DEFAULT_SHUTTER float64 = 0.5
+
+// MotionBlur blurs each frame by averaging the sub-frames captured while the
+// shutter is open.
+type MotionBlur struct {
+	// Samples is the number of sub-frames captured per frame. Motion blur is
+	// disabled below 2.
+	Samples int
+	// Shutter is the fraction of the frame interval, from 0 to 1, covered by
+	// the samples, starting at the time of the frame.
+	Shutter float64
+}
+
+// ParseMotionBlur parses a motion blur written as samples,shutter, like 8,0.5.
+// The shutter defaults to DEFAULT_SHUTTER.
+func ParseMotionBlur(value string) (MotionBlur, error) {
+	blur := MotionBlur{Shutter: DEFAULT_SHUTTER}
+	parts := strings.Split(value, ",")
+	if len(parts) > 2 {
+		return blur, fmt.Errorf("invalid motion blur %q, use samples,shutter", value)
+	}
+	samples, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || samples < 1 {
+		return blur, fmt.Errorf("invalid motion blur samples %q", parts[0])
+	}
+	blur.Samples = samples
+	if len(parts) == 2 {
+		shutter, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || shutter <= 0 || shutter > 1 {
+			return blur, fmt.Errorf("invalid motion blur shutter %q, use a fraction from 0 to 1", parts[1])
+		}
+		blur.Shutter = shutter
+	}
+	return blur, nil
+}
+
+// enabled reports whether the frames should be blurred.
+func (b MotionBlur) enabled() bool {
+	return b.Samples > 1
+}
+
+// offset returns the time of a sample relative to its frame, in ms.
+func (b MotionBlur) offset(sample int, frameDuration float64) float64 {
+	shutter := b.Shutter
+	if shutter <= 0 {
+		shutter = DEFAULT_SHUTTER
+	}
+	return shutter * frameDuration * float64(sample) / float64(b.Samples)
+}
+
+// Average blends PNG or JPEG images of the same size into a PNG image,
+// averaging their colors in linear light, weighted by alpha.
+func Average(images [][]byte) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("there are no images to average")
+	}
+	var sums []linearSum
+	var bounds image.Rectangle
+	for i, data := range images {
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			bounds = image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy())
+			sums = make([]linearSum, bounds.Dx() * bounds.Dy())
+		} else if src.Bounds().Size() != bounds.Size() {
+			return nil, fmt.Errorf("image %d measures %v instead of %v", i, src.Bounds().Size(), bounds.Size())
+		}
+		rgba, ok := src.(*image.RGBA)
+		if !ok || src.Bounds().Min != (image.Point{}) {
+			rgba = image.NewRGBA(bounds)
+			draw.Draw(rgba, bounds, src, src.Bounds().Min, draw.Src)
+		}
+		for y := 0; y < bounds.Dy(); y++ {
+			row := rgba.Pix[y * rgba.Stride:]
+			for x := 0; x < bounds.Dx(); x++ {
+				sums[y * bounds.Dx() + x].add(row[x * 4:x * 4 + 4])
+			}
+		}
+	}
+
+	dst := image.NewRGBA(bounds)
+	for i := range sums {
+		sums[i].average(dst.Pix[i * 4:], len(images))
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type BlurSuite struct {
+	suite.Suite
+}
+
+func (suite *BlurSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+// fill encodes a 2x2 PNG image of a single color.
+func (suite *BlurSuite) fill(c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	suite.NoError(png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func (suite *BlurSuite) TestParseMotionBlur() {
+	blur, err := ParseMotionBlur("8,0.25")
+	suite.NoError(err)
+	suite.Equal(MotionBlur{Samples: 8, Shutter: 0.25}, blur)
+
+	blur, err = ParseMotionBlur("4")
+	suite.NoError(err)
+	suite.Equal(MotionBlur{Samples: 4, Shutter: DEFAULT_SHUTTER}, blur)
+
+	for _, value := range []string{"", "0", "8,0", "8,1.5", "8,0.5,1"} {
+		_, err := ParseMotionBlur(value)
+		suite.Error(err, value)
+	}
+}
+
+func (suite *BlurSuite) TestAverage() {
+	data, err := Average([][]byte{suite.fill(color.White), suite.fill(color.Black)})
+	suite.NoError(err)
+	img, err := png.Decode(bytes.NewReader(data))
+	suite.NoError(err)
+	// Half the light of white is 188 in sRGB, not 128
+	r, _, _, a := img.At(1, 1).RGBA()
+	suite.Equal(uint32(0xbcbc), r)
+	suite.Equal(uint32(0xffff), a)
+
+	// A sample where the object is gone shouldn't darken its color
+	data, err = Average([][]byte{suite.fill(color.NRGBA{255, 0, 0, 255}), suite.fill(color.Transparent)})
+	suite.NoError(err)
+	img, err = png.Decode(bytes.NewReader(data))
+	suite.NoError(err)
+	c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+	suite.InDelta(255, int(c.R), 2)
+	suite.Equal([]uint8{0, 0, 128}, []uint8{c.G, c.B, c.A})
+
+	_, err = Average(nil)
+	suite.Error(err)
+}
+
+func (suite *BlurSuite) TestMotionBlur() {
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, suite.fill(color.White))
+
+	frames := make(map[int][]byte)
+	var output bytes.Buffer
+	params := RecordParams{
+		Duration: 100,
+		Workers : 2,
+		MotionBlur: MotionBlur{Samples: 4, Shutter: 0.5},
+		Sink    : NewTeeSink(NewOrderedWriter(&output, 2 * REORDER_FRAMES_PER_WORKER), &mapFrameSink{frames: frames}),
+	}
+	stats, err := record(context.Background(), params)
+	suite.NoError(err)
+
+	// Every sample of every frame should be captured
+	suite.Equal(6, stats.Frames)
+	handler.AssertNumberOfCalls(suite.T(), "Screenshot", 24)
+	for _, script := range []string{`timeweb.goTo(16.667)`, `timeweb.goTo(18.750)`, `timeweb.goTo(20.833)`, `timeweb.goTo(22.917)`} {
+		handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, script)
+	}
+	handler.AssertNotCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(25.000)`)
+	suite.Len(frames, 6)
+}
+
+// Run the test suite
+func TestBlurSuite(t *testing.T) {
+	suite.Run(t, new(BlurSuite))
+}
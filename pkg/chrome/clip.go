@@ -0,0 +1,78 @@
+package chrome
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gux.codes/omega/pkg/browser"
+)
+
+// ParseClip parses a region written as x,y,width,height in CSS pixels.
+func ParseClip(value string) (*browser.Clip, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid clip %q, use x,y,width,height", value)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clip %q, use x,y,width,height", value)
+		}
+		values[i] = v
+	}
+	clip := &browser.Clip{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+	if clip.Width <= 0 || clip.Height <= 0 {
+		return nil, fmt.Errorf("the clip %q is empty", value)
+	}
+	return clip, nil
+}
+
+// boundingBoxScript returns a script that returns the region of the first
+// element that matches the selector, or null.
+func boundingBoxScript(selector string) string {
+	quoted, _ := json.Marshal(selector)
+	return fmt.Sprintf(`(() => {
+	const element = document.querySelector(%s);
+	if (!element) return null;
+	const rect = element.getBoundingClientRect();
+	return {x: rect.x + window.scrollX, y: rect.y + window.scrollY, width: rect.width, height: rect.height};
+})()`, quoted)
+}
+
+// resolveClip returns the region of the element that matches the selector on
+// the page of the browser context.
+func resolveClip(ctx context.Context, selector string) (*browser.Clip, error) {
+	res, err := browser.Chrome.Evaluate(ctx, boundingBoxScript(selector))
+	if err != nil {
+		return nil, err
+	}
+	var clip *browser.Clip
+	if err := json.Unmarshal(res, &clip); err != nil {
+		return nil, err
+	}
+	if clip == nil {
+		return nil, fmt.Errorf("no element matches %s", selector)
+	}
+	if clip.Width <= 0 || clip.Height <= 0 {
+		return nil, fmt.Errorf("the element that matches %s is empty", selector)
+	}
+	return clip, nil
+}
+
+// evenClip grows the clip to whole device pixels and shrinks it to an even
+// size, which most encoders require.
+func evenClip(clip browser.Clip, scale float64) browser.Clip {
+	if scale <= 0 {
+		scale = 1
+	}
+	x, y := math.Floor(clip.X * scale), math.Floor(clip.Y * scale)
+	width := math.Ceil((clip.X + clip.Width) * scale) - x
+	height := math.Ceil((clip.Y + clip.Height) * scale) - y
+	width, height = math.Max(2, width - math.Mod(width, 2)), math.Max(2, height - math.Mod(height, 2))
+	return browser.Clip{X: x / scale, Y: y / scale, Width: width / scale, Height: height / scale}
+}
@@ -0,0 +1,73 @@
+package chrome
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type ClipSuite struct {
+	suite.Suite
+}
+
+func (suite *ClipSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+func (suite *ClipSuite) TestParseClip() {
+	clip, err := ParseClip("10, 20,300,150.5")
+	suite.NoError(err)
+	suite.Equal(&browser.Clip{X: 10, Y: 20, Width: 300, Height: 150.5}, clip)
+
+	for _, value := range []string{"", "10,20,300", "a,b,c,d", "0,0,0,100"} {
+		_, err := ParseClip(value)
+		suite.Error(err, value)
+	}
+}
+
+func (suite *ClipSuite) TestEvenClip() {
+	suite.Equal(browser.Clip{X: 10, Y: 5, Width: 102, Height: 52}, evenClip(browser.Clip{X: 10.3, Y: 5.5, Width: 101, Height: 51}, 1))
+	suite.Equal(browser.Clip{X: 10, Y: 5, Width: 101, Height: 51}, evenClip(browser.Clip{X: 10, Y: 5, Width: 101, Height: 51}, 2))
+}
+
+func (suite *ClipSuite) TestSelector() {
+	handler := &mbrowser.BrowserHandler{}
+	handler.On("Evaluate", mock.Anything, boundingBoxScript("#chart")).Return([]byte(`{"x":100,"y":50.5,"width":641,"height":360}`), nil)
+	handler.On("Evaluate", mock.Anything, boundingBoxScript("#missing")).Return([]byte(`null`), nil)
+	mockBrowser(handler, []byte("frame"))
+
+	suite.Run("should capture the region of the element", func() {
+		params := RecordParams{
+			Duration: 100,
+			Workers : 3,
+			Selector: "#chart",
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+		handler.AssertNumberOfCalls(suite.T(), "Screenshot", 6)
+		handler.AssertCalled(suite.T(), "Screenshot", mock.Anything, browser.ScreenshotOptions{
+			Clip: &browser.Clip{X: 100, Y: 50, Width: 640, Height: 360},
+		})
+	})
+
+	suite.Run("should fail if no element matches the selector", func() {
+		params := RecordParams{
+			Duration: 100,
+			Selector: "#missing",
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		_, err := record(context.Background(), params)
+		suite.Error(err)
+		suite.Contains(err.Error(), "#missing")
+	})
+}
+
+// Run the test suite
+func TestClipSuite(t *testing.T) {
+	suite.Run(t, new(ClipSuite))
+}
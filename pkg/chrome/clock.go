@@ -0,0 +1,98 @@
+package chrome
+
+import (
+	"context"
+	"fmt"
+
+	"gux.codes/omega/pkg/browser"
+)
+
+// TIMEWEB_TIME controls the time of the page with timeweb.js.
+const TIMEWEB_TIME string = "timeweb"
+
+// VIRTUAL_TIME controls the time of the page with the virtual time of Chrome.
+const VIRTUAL_TIME string = "virtual"
+
+// TimeController moves the clock of the pages being recorded, so every frame
+// is captured at its exact time.
+type TimeController interface {
+	// Prepare sets up a new browser context before it navigates to the page.
+	Prepare(ctx context.Context) error
+	// Seek moves the clock of the page from `from` to `to`, in ms. from is
+	// negative when the time of the page is unknown.
+	Seek(ctx context.Context, from float64, to float64) error
+	// Rewinds reports whether Seek can move the clock backwards, or from an
+	// unknown time. Otherwise, the page is loaded again to start over.
+	Rewinds() bool
+}
+
+// TimewebController sets the time of the page with `timeweb.goTo`. The page
+// must load assets/timeweb.js.
+type TimewebController struct{}
+
+// timewebScript returns the script that moves the time of the page to ms.
+func timewebScript(ms float64) string {
+	return fmt.Sprintf("timeweb.goTo(%.3f)", ms)
+}
+
+// Prepare does nothing, timeweb.js patches the page by itself.
+func (TimewebController) Prepare(ctx context.Context) error {
+	return nil
+}
+
+// Seek evaluates `timeweb.goTo` on the page.
+func (TimewebController) Seek(ctx context.Context, from float64, to float64) error {
+	_, err := browser.Chrome.Evaluate(ctx, timewebScript(to))
+	return err
+}
+
+// Rewinds returns true, timeweb.js can go to any time.
+func (TimewebController) Rewinds() bool {
+	return true
+}
+
+// VirtualTimeController pauses the clock of the browser, and advances it by
+// the time between frames. Unlike timeweb.js, it also drives workers, CSS
+// animations and videos, without changing the page.
+type VirtualTimeController struct{}
+
+// Prepare pauses the virtual time of the browser context.
+func (VirtualTimeController) Prepare(ctx context.Context) error {
+	return browser.Chrome.PauseVirtualTime(ctx)
+}
+
+// Seek advances the virtual time until it reaches `to`.
+func (VirtualTimeController) Seek(ctx context.Context, from float64, to float64) error {
+	if from < 0 || to < from {
+		return fmt.Errorf("the virtual time can't go from %.3fms back to %.3fms", from, to)
+	}
+	if to == from {
+		return nil
+	}
+	return browser.Chrome.AdvanceVirtualTime(ctx, to - from)
+}
+
+// Rewinds returns false, the virtual time only moves forward.
+func (VirtualTimeController) Rewinds() bool {
+	return false
+}
+
+// reusable reports whether the browser contexts of a recording can be reused
+// once it ends. The virtual time of a browser context can't go back to the
+// real time, so the next recording would start from where it paused.
+func reusable(clock TimeController) bool {
+	_, virtual := clock.(VirtualTimeController)
+	return !virtual
+}
+
+// TimeControllerByName returns the TimeController of a time mode: timeweb,
+// the default, or virtual.
+func TimeControllerByName(name string) (TimeController, error) {
+	switch name {
+	case "", TIMEWEB_TIME:
+		return TimewebController{}, nil
+	case VIRTUAL_TIME:
+		return VirtualTimeController{}, nil
+	}
+	return nil, fmt.Errorf("unknown time mode %q, use %s or %s", name, TIMEWEB_TIME, VIRTUAL_TIME)
+}
@@ -0,0 +1,119 @@
+package chrome
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type ClockSuite struct {
+	suite.Suite
+	handler *mbrowser.BrowserHandler
+	mu sync.Mutex
+	// budgets holds the virtual time advanced on each call.
+	budgets []float64
+}
+
+// setup mocks a browser whose screenshots fail while fail returns true.
+func (suite *ClockSuite) setup(fail func(attempt int) bool) {
+	suite.handler = &mbrowser.BrowserHandler{}
+	suite.budgets = nil
+	attempts := 0
+	suite.handler.On("AdvanceVirtualTime", mock.Anything, mock.AnythingOfType("float64")).Return(
+		func(ctx context.Context, budget float64) error {
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			suite.budgets = append(suite.budgets, budget)
+			return nil
+		},
+	)
+	suite.handler.On("Screenshot", mock.Anything, mock.Anything).Return(
+		[]byte("frame"),
+		func(ctx context.Context, options browser.ScreenshotOptions) error {
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			attempts++
+			if fail(attempts) {
+				return errors.New("screenshot failed")
+			}
+			return nil
+		},
+	)
+	mockBrowser(suite.handler, nil)
+}
+
+// elapsed returns the total virtual time advanced.
+func (suite *ClockSuite) elapsed() float64 {
+	total := 0.0
+	for _, budget := range suite.budgets {
+		total += budget
+	}
+	return total
+}
+
+func (suite *ClockSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+func (suite *ClockSuite) TestVirtualTime() {
+	suite.Run("should advance the virtual time between frames", func() {
+		suite.setup(func(attempt int) bool { return false })
+		params := RecordParams{
+			Duration: 100,
+			Time    : VIRTUAL_TIME,
+			Workers : 1,
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(6, stats.Frames)
+		suite.Len(suite.budgets, 5)
+		suite.InDelta(83.333, suite.elapsed(), 0.001)
+		suite.handler.AssertNotCalled(suite.T(), "Evaluate", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("should load the page again to retry a frame", func() {
+		// Fail the screenshot of the fourth frame once
+		suite.setup(func(attempt int) bool { return attempt == 4 })
+		params := RecordParams{
+			Duration: 100,
+			Time    : VIRTUAL_TIME,
+			Workers : 1,
+			Retries : 1,
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(6, stats.Frames)
+		suite.handler.AssertNumberOfCalls(suite.T(), "Navigate", 2)
+		suite.handler.AssertNumberOfCalls(suite.T(), "PauseVirtualTime", 2)
+		// The new page should run from 0 to the failing frame
+		suite.InDelta(50, suite.budgets[3], 0.001)
+		suite.InDelta(83.333 + 50, suite.elapsed(), 0.001)
+	})
+}
+
+func (suite *ClockSuite) TestTimeControllerByName() {
+	controller, err := TimeControllerByName("")
+	suite.NoError(err)
+	suite.IsType(TimewebController{}, controller)
+
+	controller, err = TimeControllerByName(VIRTUAL_TIME)
+	suite.NoError(err)
+	suite.False(controller.Rewinds())
+	suite.Error(controller.Seek(context.Background(), 100, 50))
+
+	_, err = TimeControllerByName("sundial")
+	suite.Error(err)
+}
+
+// Run the test suite
+func TestClockSuite(t *testing.T) {
+	suite.Run(t, new(ClockSuite))
+}
@@ -0,0 +1,28 @@
+package chrome
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadData reads the user data passed to the templates from a JSON or YAML
+// file. Files are parsed as JSON if their extension is `.json`, and as YAML
+// otherwise.
+func LoadData(path string) (interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(content, &data)
+	} else {
+		err = yaml.Unmarshal(content, &data)
+	}
+	return data, err
+}
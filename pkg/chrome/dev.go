@@ -59,7 +59,7 @@ func NewDev(options DevOptions) error {
 
 	// Run an initial build
 	result := build.Run()
-	buildDone(result)
+	_ = buildDone(result)
 	utils.Info("Initial build done")
 
 	// Redirect to the dev site
@@ -71,7 +71,7 @@ func NewDev(options DevOptions) error {
 
 	stop := build.WithWatch(func (result api.BuildResult) {
 		utils.Info("Build done")
-		buildDone(result)
+		_ = buildDone(result)
 		chromedp.Run(ctx, chromedp.Reload())
 		utils.Info("Reloading...")
 	}).Run().Stop
@@ -95,14 +95,18 @@ func NewDev(options DevOptions) error {
 	return nil
 }
 
-func buildDone(result api.BuildResult) {
+// buildDone stores the output files of a build, to be served under /dev. It
+// prints and returns the build errors, if any.
+func buildDone(result api.BuildResult) error {
 	if len(result.Errors) > 0 {
 		utils.Error("Some errors were encountered while building...")
 		for _, err := range result.Errors {
-			fmt.Printf("Location: at %s on line %d\n", err.Location.File, err.Location.Line)
+			if err.Location != nil {
+				fmt.Printf("Location: at %s on line %d\n", err.Location.File, err.Location.Line)
+			}
 			fmt.Printf("Reason  : %s\n", err.Text)
 		}
-		return
+		return fmt.Errorf("%d errors building the project", len(result.Errors))
 	}
 	if len(result.OutputFiles) > 0 {
 		build.Set("script.js", result.OutputFiles[0].Contents)
@@ -110,4 +114,12 @@ func buildDone(result api.BuildResult) {
 	if len(result.OutputFiles) > 1 {
 		build.Set("styles.css", result.OutputFiles[1].Contents)
 	}
+	return nil
+}
+
+// buildProject runs a one-shot build of the entry point, to be served under
+// /dev like the development environment does.
+func buildProject(entryPoint string) error {
+	build.WithEntrypoints([]string{entryPoint})
+	return buildDone(build.Run())
 }
@@ -0,0 +1,34 @@
+package chrome
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DevSuite struct {
+	suite.Suite
+}
+
+func (suite *DevSuite) TestBuildProject() {
+	suite.Run("should serve the bundle of the entry point", func() {
+		dir := suite.T().TempDir()
+		suite.NoError(ioutil.WriteFile(filepath.Join(dir, "scene.js"), []byte("export const scene = 'omega scene';"), 0644))
+		suite.NoError(ioutil.WriteFile(filepath.Join(dir, "index.js"), []byte("import { scene } from './scene.js';\nconsole.log(scene);"), 0644))
+		suite.NoError(buildProject(filepath.Join(dir, "index.js")))
+		suite.Contains(string(build.Get("script.js")), "omega scene")
+	})
+
+	suite.Run("should return the build errors", func() {
+		dir := suite.T().TempDir()
+		suite.NoError(ioutil.WriteFile(filepath.Join(dir, "index.js"), []byte("const = ;"), 0644))
+		suite.Error(buildProject(filepath.Join(dir, "index.js")))
+	})
+}
+
+// Run the test suite
+func TestDevSuite(t *testing.T) {
+	suite.Run(t, new(DevSuite))
+}
@@ -0,0 +1,199 @@
+package chrome
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"runtime"
+	"sync"
+)
+
+// Downscale shrinks a PNG or JPEG image by an integer factor with a box filter, which
+// averages each factor by factor block of pixels into one, into a PNG image. The average is
+// done in linear light and weighted by alpha, so neither the edges of shapes nor transparent
+// pixels darken the result.
+func Downscale(data []byte, factor int) ([]byte, error) {
+	if factor <= 1 {
+		return data, nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on premultiplied RGBA pixels
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+	width, height := bounds.Dx() / factor, bounds.Dy() / factor
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("a %dx%d image can't be downscaled %d times", bounds.Dx(), bounds.Dy(), factor)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum linearSum
+			for sy := y * factor; sy < (y + 1) * factor; sy++ {
+				row := rgba.Pix[sy * rgba.Stride + x * factor * 4:]
+				for i := 0; i < factor * 4; i += 4 {
+					sum.add(row[i:i + 4])
+				}
+			}
+			sum.average(dst.Pix[y * dst.Stride + x * 4:], factor * factor)
+		}
+	}
+
+	// The encoder isn't the place to save bytes, ffmpeg compresses the video
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downscaleJob is a frame waiting to be downscaled.
+type downscaleJob struct {
+	index int
+	image []byte
+}
+
+// Downscaler is a FrameSink that downscales the frames of a supersampled
+// recording on a pool of goroutines before writing them to another sink, so
+// the capture doesn't wait for them. Like the OrderedWriter, it only accepts
+// frames within capacity of the first frame that wasn't written yet, so a
+// downstream OrderedWriter of the same capacity never blocks the pool.
+type Downscaler struct {
+	sink FrameSink
+	factor int
+	capacity int
+	jobs chan downscaleJob
+	wg sync.WaitGroup
+	mu sync.Mutex
+	cond *sync.Cond
+	// next is the index of the first frame that wasn't written yet.
+	next int
+	// written holds the frames written after next.
+	written map[int]bool
+	err error
+	closed bool
+}
+
+// NewDownscaler creates a Downscaler that shrinks the frames by factor and
+// writes them to sink. It runs a goroutine per CPU.
+func NewDownscaler(sink FrameSink, factor int, capacity int) *Downscaler {
+	if capacity < 1 {
+		capacity = 1
+	}
+	d := &Downscaler{
+		sink: sink,
+		factor: factor,
+		capacity: capacity,
+		jobs: make(chan downscaleJob, capacity),
+		written: make(map[int]bool),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	for i := 0; i < runtime.NumCPU(); i++ {
+		d.wg.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// WriteFrame queues the frame to be downscaled. It blocks while the frame is
+// too far ahead of the first frame that wasn't written yet.
+func (d *Downscaler) WriteFrame(index int, image []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.err == nil && index >= d.next + d.capacity {
+		d.cond.Wait()
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if d.closed {
+		return fmt.Errorf("frame %d was written after closing the sink", index)
+	}
+	// The frame is within capacity, so there is room on the queue
+	d.jobs <- downscaleJob{index: index, image: image}
+	return nil
+}
+
+// run downscales the queued frames and writes them to the sink.
+func (d *Downscaler) run() {
+	defer d.wg.Done()
+	for job := range d.jobs {
+		if d.Err() == nil {
+			image, err := Downscale(job.image, d.factor)
+			if err != nil {
+				d.fail(&FrameError{Frame: job.index, Op: "downscale", Err: err})
+			} else if err := d.sink.WriteFrame(job.index, image); err != nil {
+				d.fail(err)
+			}
+		}
+		d.done(job.index)
+	}
+}
+
+// done marks the frame as written and moves the window of accepted frames.
+func (d *Downscaler) done(index int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written[index] = true
+	for d.written[d.next] {
+		delete(d.written, d.next)
+		d.next++
+	}
+	d.cond.Broadcast()
+}
+
+// fail keeps the first error and releases the blocked writers.
+func (d *Downscaler) fail(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		d.err = err
+	}
+	d.cond.Broadcast()
+}
+
+// Err returns the first error of the downscaler.
+func (d *Downscaler) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// stop closes the queue, so the goroutines end once it is empty.
+func (d *Downscaler) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		close(d.jobs)
+	}
+}
+
+// Close waits for the queued frames and closes the sink.
+func (d *Downscaler) Close() error {
+	d.stop()
+	d.wg.Wait()
+	if err := d.Err(); err != nil {
+		return err
+	}
+	return d.sink.Close()
+}
+
+// Abort drops the queued frames and aborts the sink.
+func (d *Downscaler) Abort(err error) {
+	d.fail(err)
+	d.stop()
+	abort(d.sink, err)
+}
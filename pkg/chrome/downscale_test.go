@@ -0,0 +1,129 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type DownscaleSuite struct {
+	suite.Suite
+}
+
+func (suite *DownscaleSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+// checkerboard encodes a PNG image of alternating black and white pixels.
+func (suite *DownscaleSuite) checkerboard(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x + y) % 2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	suite.NoError(png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func (suite *DownscaleSuite) TestDownscale() {
+	suite.Run("should average each block of pixels", func() {
+		data, err := Downscale(suite.checkerboard(4, 6), 2)
+		suite.NoError(err)
+		img, err := png.Decode(bytes.NewReader(data))
+		suite.NoError(err)
+		suite.Equal(image.Rect(0, 0, 2, 3), img.Bounds())
+		// Half the light of white is 188 in sRGB, not 128
+		r, g, b, a := img.At(1, 2).RGBA()
+		suite.Equal([]uint32{0xbcbc, 0xbcbc, 0xbcbc, 0xffff}, []uint32{r, g, b, a})
+	})
+
+	suite.Run("should keep the color of semi-transparent edges", func() {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		img.Set(0, 0, color.NRGBA{200, 100, 50, 255})
+		img.Set(1, 0, color.NRGBA{200, 100, 50, 128})
+		var buf bytes.Buffer
+		suite.NoError(png.Encode(&buf, img))
+		data, err := Downscale(buf.Bytes(), 2)
+		suite.NoError(err)
+		out, err := png.Decode(bytes.NewReader(data))
+		suite.NoError(err)
+		c := color.NRGBAModel.Convert(out.At(0, 0)).(color.NRGBA)
+		suite.InDelta(96, int(c.A), 1)
+		suite.InDelta(200, int(c.R), 2)
+		suite.InDelta(100, int(c.G), 2)
+		suite.InDelta(50, int(c.B), 2)
+	})
+
+	suite.Run("should fail on images smaller than the factor", func() {
+		_, err := Downscale(suite.checkerboard(1, 1), 2)
+		suite.Error(err)
+	})
+}
+
+func (suite *DownscaleSuite) TestDownscaler() {
+	// Write the frames out of order from several goroutines
+	var output bytes.Buffer
+	downscaler := NewDownscaler(NewOrderedWriter(&output, 4), 1, 4)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for f := w * 10; f < (w + 1) * 10; f++ {
+				suite.NoError(downscaler.WriteFrame(f, []byte{byte(f)}))
+			}
+		}(w)
+	}
+	wg.Wait()
+	suite.NoError(downscaler.Close())
+
+	expected := make([]byte, 40)
+	for i := range expected {
+		expected[i] = byte(i)
+	}
+	suite.Equal(expected, output.Bytes())
+}
+
+func (suite *DownscaleSuite) TestSupersample() {
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, suite.checkerboard(8, 8))
+
+	frames := make(map[int][]byte)
+	params := RecordParams{
+		Duration: 100,
+		Workers : 2,
+		Width   : 4,
+		Height  : 4,
+		Supersample: 2,
+	}
+	params.Sink = params.process(&mapFrameSink{frames: frames})
+	_, err := record(context.Background(), params)
+	suite.NoError(err)
+	suite.NoError(params.Sink.Close())
+
+	// The page should be captured at twice the resolution
+	handler.AssertCalled(suite.T(), "Navigate", mock.Anything, mock.Anything, browser.Viewport{Width: 4, Height: 4, DeviceScaleFactor: 2})
+	suite.Equal(params.frames(), len(frames))
+	img, err := png.Decode(bytes.NewReader(frames[0]))
+	suite.NoError(err)
+	suite.Equal(image.Rect(0, 0, 4, 4), img.Bounds())
+}
+
+// Run the test suite
+func TestDownscaleSuite(t *testing.T) {
+	suite.Run(t, new(DownscaleSuite))
+}
@@ -0,0 +1,291 @@
+package chrome
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gux.codes/omega/pkg/browser"
+)
+
+// Preset holds the ffmpeg output settings of a container and codec.
+type Preset struct {
+	// Name used to select the preset.
+	Name string
+	// Extension of the output file.
+	Extension string
+	// Args are the ffmpeg output arguments.
+	Args []string
+	// Alpha reports whether the preset keeps the alpha channel of the frames.
+	Alpha bool
+	// Sequence reports whether the preset writes a file per frame.
+	Sequence bool
+}
+
+// DEFAULT_PRESET is the preset used when none is provided.
+const DEFAULT_PRESET string = "h264"
+
+// ALPHA_PRESET is the preset used for transparent recordings when the chosen
+// one drops the alpha channel.
+const ALPHA_PRESET string = "prores4444"
+
+// DRAFT_PRESET is the preset of draft recordings.
+const DRAFT_PRESET string = "h264-fast"
+
+// Presets holds the available presets by name. See docs/ffmpeg.md.
+var Presets = map[string]Preset{
+	"h264": {
+		Name: "h264",
+		Extension: "mp4",
+		Args: []string{`-c:v`, `libx264`, `-pix_fmt`, `yuv420p`},
+	},
+	"prores4444": {
+		Name: "prores4444",
+		Extension: "mov",
+		Args: []string{`-c:v`, `prores_ks`, `-profile:v`, `4444`, `-pix_fmt`, `yuva444p10le`, `-alpha_bits`, `16`},
+		Alpha: true,
+	},
+	"vp9-alpha": {
+		Name: "vp9-alpha",
+		Extension: "webm",
+		Args: []string{`-c:v`, `vp9`, `-pix_fmt`, `yuva420p`},
+		Alpha: true,
+	},
+	"gif": {
+		Name: "gif",
+		Extension: "gif",
+		Args: []string{`-vf`, `split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse`, `-loop`, `0`},
+	},
+	"h264-fast": {
+		Name: "h264-fast",
+		Extension: "mp4",
+		Args: []string{`-c:v`, `libx264`, `-preset`, `ultrafast`, `-crf`, `28`, `-pix_fmt`, `yuv420p`},
+	},
+	"png-sequence": {
+		Name: "png-sequence",
+		Extension: "png",
+		Args: []string{`-c:v`, `png`, `-f`, `image2`},
+		Alpha: true,
+		Sequence: true,
+	},
+}
+
+// PresetNames returns the sorted names of the available presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetByName returns the preset with the provided name, or the default
+// preset if the name is empty.
+func PresetByName(name string) (Preset, error) {
+	if name == "" {
+		name = DEFAULT_PRESET
+	}
+	preset, ok := Presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q, use one of: %s", name, strings.Join(PresetNames(), ", "))
+	}
+	return preset, nil
+}
+
+// OutputPath returns the path ffmpeg writes to. It defaults to
+// /tmp/out.{{ extension }}, or to the /tmp/out directory for sequences. A
+// sequence output without a `%d` pattern is taken as a directory.
+func (p Preset) OutputPath(output string) string {
+	if output == "" {
+		output = "/tmp/out"
+		if !p.Sequence {
+			output += "." + p.Extension
+		}
+	}
+	if p.Sequence && !strings.Contains(output, "%") {
+		output = filepath.Join(output, "%06d." + p.Extension)
+	}
+	return output
+}
+
+// frameRateFraction returns the numerator and denominator of a frame rate.
+// NTSC rates, like 29.97, are the exact fraction they are rounded from, like
+// 30000/1001, and other rates are divided by 1.
+func frameRateFraction(fps float64) (float64, float64) {
+	if n := math.Round(fps * 1.001); n != fps && math.Abs(fps - n / 1.001) < 0.005 {
+		return n * 1000, 1001
+	}
+	return fps, 1
+}
+
+// FrameRate formats a frame rate for ffmpeg. NTSC rates, like 29.97, are
+// written as their exact fraction, like 30000/1001.
+func FrameRate(fps float64) string {
+	num, den := frameRateFraction(fps)
+	if den != 1 {
+		return fmt.Sprintf("%d/%d", int64(num), int64(den))
+	}
+	return strconv.FormatFloat(fps, 'f', -1, 64)
+}
+
+// inputCodecs holds the ffmpeg decoder of each frame format.
+var inputCodecs = map[string]string{
+	browser.FormatPNG: `png`,
+	browser.FormatJPEG: `mjpeg`,
+	browser.FormatWebP: `webp`,
+}
+
+// FormatExtension returns the file extension of a frame format.
+func FormatExtension(format string) string {
+	switch format {
+	case "":
+		return "png"
+	case browser.FormatJPEG:
+		return "jpg"
+	}
+	return format
+}
+
+// FFmpegArgs returns the arguments of an ffmpeg command that reads the frames
+// from stdin, in the provided format and frame rate, and encodes them with the
+// preset. The extra arguments are added right before the output path, so they
+// can override the preset.
+func (p Preset) FFmpegArgs(fps float64, format string, output string, extra []string) []string {
+	rate := FrameRate(fps)
+	codec, ok := inputCodecs[format]
+	if !ok {
+		codec = inputCodecs[browser.FormatPNG]
+	}
+	args := []string{`-y`, `-f`, `image2pipe`, `-c:v`, codec, `-framerate`, rate, `-i`, `pipe:0`}
+	args = append(args, p.Args...)
+	args = append(args, `-r`, rate)
+	args = append(args, extra...)
+	return append(args, output)
+}
+
+// ParseFFmpegArgs splits extra ffmpeg arguments like a shell does: arguments
+// are separated by spaces, unless they are quoted or escaped with a
+// backslash, so `-vf "scale=1280:-1, fps=30"` is two arguments.
+func ParseFFmpegArgs(value string) ([]string, error) {
+	args := make([]string, 0)
+	var arg strings.Builder
+	// inArg is true once the current argument started, even if it is empty
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			// A backslash only escapes quotes and backslashes between double
+			// quotes
+			if quote == '"' && r != '"' && r != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("invalid ffmpeg arguments %q, a quote or escape isn't closed", value)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// FFmpegSink is a FrameSink that encodes the frames with an ffmpeg process.
+// Frames reach the stdin of ffmpeg in order through an OrderedWriter.
+type FFmpegSink struct {
+	*OrderedWriter
+	// Output is the path ffmpeg writes to.
+	Output string
+	preset Preset
+	cmd *exec.Cmd
+	stdin io.WriteCloser
+	once sync.Once
+}
+
+// NewFFmpegSink starts an ffmpeg process that encodes the frames, images of
+// the provided format, with the preset. capacity is the size of the reorder
+// buffer, see NewOrderedWriter.
+func NewFFmpegSink(preset Preset, fps float64, format string, output string, extra []string, capacity int) (*FFmpegSink, error) {
+	output = preset.OutputPath(output)
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(`ffmpeg`, preset.FFmpegArgs(fps, format, output, extra)...)
+
+	// Pipe cmd stderr and stdout to the console
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+
+	// Open stdin pipe
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the ffmpeg command
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &FFmpegSink{
+		OrderedWriter: NewOrderedWriter(stdin, capacity),
+		Output: output,
+		preset: preset,
+		cmd: cmd,
+		stdin: stdin,
+	}, nil
+}
+
+// Close closes the stdin of ffmpeg, or it will wait forever, and waits until
+// it finishes the encoding.
+func (s *FFmpegSink) Close() error {
+	if err := s.OrderedWriter.Close(); err != nil {
+		s.Abort(err)
+		return err
+	}
+	if err := s.stdin.Close(); err != nil {
+		return err
+	}
+	return s.cmd.Wait()
+}
+
+// Abort stops ffmpeg and removes the truncated output. Sequences are kept.
+func (s *FFmpegSink) Abort(err error) {
+	s.OrderedWriter.Abort(err)
+	s.once.Do(func() {
+		_ = s.stdin.Close()
+		_ = s.cmd.Process.Kill()
+		_ = s.cmd.Wait()
+		if !s.preset.Sequence {
+			_ = os.Remove(s.Output)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package chrome
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FFmpegSuite struct {
+	suite.Suite
+}
+
+func (suite *FFmpegSuite) TestPresetByName() {
+	suite.Run("should default to h264", func() {
+		preset, err := PresetByName("")
+		suite.NoError(err)
+		suite.Equal("h264", preset.Name)
+	})
+
+	suite.Run("should fail on unknown presets", func() {
+		_, err := PresetByName("divx")
+		suite.Error(err)
+		suite.Contains(err.Error(), "prores4444")
+	})
+}
+
+func (suite *FFmpegSuite) TestOutputPath() {
+	suite.Run("should default to /tmp/out with the preset extension", func() {
+		suite.Equal("/tmp/out.mp4", Presets["h264"].OutputPath(""))
+		suite.Equal("/tmp/out.mov", Presets["prores4444"].OutputPath(""))
+	})
+
+	suite.Run("should keep the provided output", func() {
+		suite.Equal("./intro.webm", Presets["vp9-alpha"].OutputPath("./intro.webm"))
+	})
+
+	suite.Run("should write sequences to a directory", func() {
+		suite.Equal("/tmp/out/%06d.png", Presets["png-sequence"].OutputPath(""))
+		suite.Equal("frames/%06d.png", Presets["png-sequence"].OutputPath("frames"))
+		suite.Equal("frames/%04d.png", Presets["png-sequence"].OutputPath("frames/%04d.png"))
+	})
+}
+
+func (suite *FFmpegSuite) TestFFmpegArgs() {
+	suite.Run("should build the h264 command", func() {
+		suite.Equal([]string{
+			`-y`,
+			`-f`, `image2pipe`,
+			`-c:v`, `png`,
+			`-framerate`, `60`,
+			`-i`, `pipe:0`,
+			`-c:v`, `libx264`,
+			`-pix_fmt`, `yuv420p`,
+			`-r`, `60`,
+			`/tmp/out.mp4`,
+		}, Presets["h264"].FFmpegArgs(60, "png", "/tmp/out.mp4", nil))
+	})
+
+	suite.Run("should decode the frames with the codec of their format", func() {
+		args := Presets["h264"].FFmpegArgs(60, "jpeg", "/tmp/out.mp4", nil)
+		suite.Equal([]string{`-f`, `image2pipe`, `-c:v`, `mjpeg`}, args[1:5])
+		args = Presets["vp9-alpha"].FFmpegArgs(60, "webp", "/tmp/out.webm", nil)
+		suite.Equal(`webp`, args[4])
+	})
+
+	suite.Run("should add the extra arguments before the output", func() {
+		args := Presets["prores4444"].FFmpegArgs(25, "png", "out.mov", []string{`-vendor`, `apl0`})
+		suite.Equal([]string{`-vendor`, `apl0`, `out.mov`}, args[len(args) - 3:])
+		suite.Contains(args, `yuva444p10le`)
+	})
+}
+
+func (suite *FFmpegSuite) TestParseFFmpegArgs() {
+	args, err := ParseFFmpegArgs(`-crf 18  -vendor apl0`)
+	suite.NoError(err)
+	suite.Equal([]string{`-crf`, `18`, `-vendor`, `apl0`}, args)
+
+	// Quoted and escaped spaces should be kept in the argument
+	args, err = ParseFFmpegArgs(`-vf "scale=1280:-1, fps=30" -metadata 'title=My "video"' -metadata comment=a\ b`)
+	suite.NoError(err)
+	suite.Equal([]string{`-vf`, `scale=1280:-1, fps=30`, `-metadata`, `title=My "video"`, `-metadata`, `comment=a b`}, args)
+
+	args, err = ParseFFmpegArgs(`-metadata title="" "a\"b\c"`)
+	suite.NoError(err)
+	suite.Equal([]string{`-metadata`, `title=`, `a"b\c`}, args)
+
+	args, err = ParseFFmpegArgs(``)
+	suite.NoError(err)
+	suite.Empty(args)
+
+	_, err = ParseFFmpegArgs(`-vf "scale=1280:-1`)
+	suite.Error(err)
+}
+
+func (suite *FFmpegSuite) TestFormatExtension() {
+	suite.Equal("png", FormatExtension(""))
+	suite.Equal("jpg", FormatExtension("jpeg"))
+	suite.Equal("webp", FormatExtension("webp"))
+}
+
+func (suite *FFmpegSuite) TestFrameRate() {
+	suite.Equal("60", FrameRate(60))
+	suite.Equal("25", FrameRate(25))
+	suite.Equal("12.5", FrameRate(12.5))
+	suite.Equal("24000/1001", FrameRate(23.976))
+	suite.Equal("30000/1001", FrameRate(29.97))
+	suite.Equal("60000/1001", FrameRate(59.94))
+}
+
+// Run the test suite
+func TestFFmpegSuite(t *testing.T) {
+	suite.Run(t, new(FFmpegSuite))
+}
@@ -0,0 +1,92 @@
+package chrome
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stretchr/testify/mock"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+// workerKey numbers the browser contexts created by the mocked browsers.
+type workerKey struct{}
+
+// mockBrowser sets browser.Chrome to the handler, whose browser contexts load
+// any page, evaluate every script to nothing and capture the screenshot. The
+// expectations set on the handler before are matched first, so tests can
+// override any of these.
+func mockBrowser(handler *mbrowser.BrowserHandler, screenshot []byte) *mbrowser.BrowserHandler {
+	browser.Chrome = handler
+	var contexts int32
+	handler.On("NewContext", mock.Anything).Return(
+		func(parent context.Context) context.Context {
+			return context.WithValue(parent, workerKey{}, int(atomic.AddInt32(&contexts, 1)) - 1)
+		},
+		func(parent context.Context) context.CancelFunc { return func() {} },
+	)
+	handler.On("Navigate", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	handler.On("Reset", mock.Anything).Return(nil)
+	handler.On("PauseVirtualTime", mock.Anything).Return(nil)
+	handler.On("AdvanceVirtualTime", mock.Anything, mock.AnythingOfType("float64")).Return(nil)
+	handler.On("Evaluate", mock.Anything, mock.AnythingOfType("string")).Return(nil, nil)
+	handler.On("Screenshot", mock.Anything, mock.Anything).Return(screenshot, nil)
+	return handler
+}
+
+// pageTimes remembers the last script evaluated on each browser context, which
+// moves its page to a time.
+type pageTimes struct {
+	mu sync.Mutex
+	times map[context.Context]string
+}
+
+// evaluate mocks the evaluation of the script on the browser context.
+func (p *pageTimes) evaluate(ctx context.Context, script string) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.times == nil {
+		p.times = make(map[context.Context]string)
+	}
+	p.times[ctx] = script
+	return nil
+}
+
+// time returns the script that moved the page of the browser context to its
+// current time.
+func (p *pageTimes) time(ctx context.Context) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if script, ok := p.times[ctx]; ok {
+		return script
+	}
+	return goTo(0)
+}
+
+// goTo returns the script that sends a browser context to the frame.
+func goTo(frame int) string {
+	return fmt.Sprintf("timeweb.goTo(%.3f)", float64(frame) * 1000 / DEFAULT_FPS)
+}
+
+// mapFrameSink keeps the frames in a map.
+type mapFrameSink struct {
+	mu sync.Mutex
+	frames map[int][]byte
+}
+
+func (w *mapFrameSink) WriteFrame(index int, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.frames[index] = data
+	return nil
+}
+
+func (w *mapFrameSink) Close() error {
+	return nil
+}
+
+func (w *mapFrameSink) Err() error {
+	return nil
+}
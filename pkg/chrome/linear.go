@@ -0,0 +1,89 @@
+package chrome
+
+import (
+	"math"
+	"sync"
+)
+
+// srgbToLinear maps the 8-bit sRGB components to linear light, from 0 to 1.
+var srgbToLinear [256]float64
+
+// linearToSRGB maps linear light, quantized to 16 bits, to sRGB components,
+// from 0 to 1. It is built on first use.
+var linearToSRGB []float32
+var linearToSRGBOnce sync.Once
+
+func init() {
+	for i := range srgbToLinear {
+		srgbToLinear[i] = linearize(float64(i) / 255)
+	}
+}
+
+// toSRGB converts linear light, from 0 to 1, to a sRGB component.
+func toSRGB(v float64) float64 {
+	linearToSRGBOnce.Do(func() {
+		linearToSRGB = make([]float32, 1 << 16)
+		for i := range linearToSRGB {
+			linearToSRGB[i] = float32(delinearize(float64(i) / (1 << 16 - 1)))
+		}
+	})
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 1
+	}
+	return float64(linearToSRGB[int(v * (1 << 16 - 1) + 0.5)])
+}
+
+// linearize converts a sRGB component, from 0 to 1, to linear light.
+func linearize(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v + 0.055) / 1.055, 2.4)
+}
+
+// delinearize converts linear light, from 0 to 1, to a sRGB component.
+func delinearize(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055 * math.Pow(v, 1 / 2.4) - 0.055
+}
+
+// linearSum adds up premultiplied RGBA pixels in linear light, so averages of
+// light and dark pixels keep their brightness instead of darkening. The
+// colors are weighted by their alpha, so transparent pixels don't darken the
+// edges either.
+type linearSum [4]float64
+
+// add adds a pixel of an image.RGBA, with 8-bit premultiplied sRGB colors.
+func (s *linearSum) add(pixel []uint8) {
+	a := pixel[3]
+	if a == 0 {
+		return
+	}
+	alpha := float64(a) / 255
+	for c := 0; c < 3; c++ {
+		if a == 255 {
+			s[c] += srgbToLinear[pixel[c]]
+		} else {
+			s[c] += linearize(math.Min(float64(pixel[c]) / float64(a), 1)) * alpha
+		}
+	}
+	s[3] += alpha
+}
+
+// average writes the average of n pixels to a pixel of an image.RGBA.
+func (s *linearSum) average(pixel []uint8, n int) {
+	if s[3] == 0 {
+		pixel[0], pixel[1], pixel[2], pixel[3] = 0, 0, 0, 0
+		return
+	}
+	alpha := s[3] / float64(n)
+	for c := 0; c < 3; c++ {
+		pixel[c] = uint8(toSRGB(s[c] / s[3]) * alpha * 255 + 0.5)
+	}
+	pixel[3] = uint8(alpha * 255 + 0.5)
+}
@@ -0,0 +1,132 @@
+package chrome
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"gux.codes/omega/pkg/browser"
+	"gux.codes/omega/pkg/utils"
+)
+
+// Command is a message logged as JSON by assets/omega.js through
+// console.info.
+type Command struct {
+	// Type is either command or message.
+	Type string `json:"type"`
+	// Action of a command: start, stop, done or screenshot.
+	Action string `json:"action"`
+	Message string `json:"message"`
+}
+
+// ParseCommand parses a console message logged by Omega.js. It returns false
+// if the message wasn't logged by Omega.js.
+func ParseCommand(text string) (Command, bool) {
+	var command Command
+	if err := json.Unmarshal([]byte(text), &command); err != nil {
+		return command, false
+	}
+	return command, command.Type == "command" || command.Type == "message"
+}
+
+// OMEGA_PRESENT_SCRIPT checks whether the page loaded assets/omega.js.
+const OMEGA_PRESENT_SCRIPT string = `typeof window.Omega === "object" && typeof window.Omega.start === "function"`
+
+// omegaPresent reports whether the page of the browser context loaded
+// Omega.js.
+func omegaPresent(ctx context.Context) (bool, error) {
+	res, err := browser.Chrome.Evaluate(ctx, OMEGA_PRESENT_SCRIPT)
+	if err != nil {
+		return false, err
+	}
+	var present bool
+	if err := json.Unmarshal(res, &present); err != nil {
+		return false, nil
+	}
+	return present, nil
+}
+
+// protocol follows the Omega.js commands logged by the pages of the workers
+// while they record. The recording starts at the first frame where the page
+// calls `Omega.start()`, or at frame 0, and ends with the first frame where
+// it calls `Omega.stop()` or `Omega.done()`, or with the Duration. The workers
+// capture their frames out of order, so the spool holds them until every frame
+// before the start was reported, and the frames after the end are no longer
+// scheduled. The recording starts at frame 0 when following the protocol.
+type protocol struct {
+	mu sync.Mutex
+	// end is the frame after the last one to record.
+	end int
+	// next is the first frame whose commands weren't handled.
+	next int
+	// logged holds the commands reported after the next frame.
+	logged map[int][]Command
+	// started is true once the start of the recording is known.
+	started bool
+	scheduler *scheduler
+	spool *spool
+}
+
+// newProtocol creates a protocol that records up to end frames.
+func newProtocol(end int) *protocol {
+	return &protocol{end: end, logged: make(map[int][]Command)}
+}
+
+// report handles the commands logged while a page moved to the frame f. The
+// commands are handled in the order of the frames, so messages are printed in
+// the order the page logged them. A frame is only handled once, even if it is
+// captured again after an error.
+func (p *protocol) report(f int, commands []Command) error {
+	p.mu.Lock()
+	if _, ok := p.logged[f]; ok || f < p.next || f >= p.end {
+		p.mu.Unlock()
+		return nil
+	}
+
+	// Stop scheduling the frames after the end as soon as it is known
+	cut := false
+	for _, command := range commands {
+		switch {
+		case command.Type != "command":
+		case command.Action == "screenshot":
+			p.mu.Unlock()
+			return errors.New("Omega.screenshot() is not supported, capture the frame with omega chrome still instead")
+		case (command.Action == "stop" || command.Action == "done") && f + 1 < p.end:
+			p.end, cut = f + 1, true
+		}
+	}
+	p.logged[f] = commands
+
+	start := -1
+	for ; p.next < p.end; p.next++ {
+		commands, ok := p.logged[p.next]
+		if !ok {
+			break
+		}
+		delete(p.logged, p.next)
+		for _, command := range commands {
+			switch {
+			case command.Type == "message":
+				utils.Info(command.Message)
+			case command.Action == "start" && !p.started:
+				p.started, start = true, p.next
+			}
+		}
+	}
+	// Record every frame if the page never calls start
+	if !p.started && p.next >= p.end {
+		p.started, start = true, 0
+	}
+	end := p.end
+	p.mu.Unlock()
+
+	if cut {
+		p.scheduler.cut(end)
+		p.spool.cut(end)
+	}
+	if start >= 0 {
+		return p.spool.begin(start)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type OmegaSuite struct {
+	suite.Suite
+	handler *mbrowser.BrowserHandler
+	mu sync.Mutex
+	// listeners holds the contexts passed to OnConsole.
+	listeners []context.Context
+}
+
+func (suite *OmegaSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+// setup mocks a page that logs the provided console messages when its time
+// moves past their frame, on every browser context. The messages of frame 0
+// are logged while the page loads. The page doesn't load Omega.js if messages
+// is nil.
+func (suite *OmegaSuite) setup(params RecordParams, messages map[int]string) {
+	suite.handler = &mbrowser.BrowserHandler{}
+	suite.listeners = nil
+	var times pageTimes
+	consoles := make(map[interface{}]func(string))
+	shown := make(map[interface{}]int)
+	frames := make(map[string]int)
+	for f := 0; f < params.frames(); f++ {
+		frames[params.goTo(f)] = f
+	}
+	// log sends the messages of the frames after the one shown by the page, up
+	// to the frame f.
+	log := func(ctx context.Context, f int) {
+		suite.mu.Lock()
+		key := ctx.Value(workerKey{})
+		from, console := shown[key], consoles[key]
+		shown[key] = f
+		suite.mu.Unlock()
+		if from >= f {
+			from = f - 1
+		}
+		for frame := from + 1; frame <= f && console != nil; frame++ {
+			if message, ok := messages[frame]; ok {
+				console(message)
+			}
+		}
+	}
+
+	suite.handler.On("OnConsole", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+		suite.listeners = append(suite.listeners, ctx)
+		consoles[ctx.Value(workerKey{})] = args.Get(1).(func(string))
+	})
+	suite.handler.On("Navigate", mock.Anything, params.URL, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		log(args.Get(0).(context.Context), 0)
+	})
+	suite.handler.On("Evaluate", mock.Anything, OMEGA_PRESENT_SCRIPT).Return([]byte(fmt.Sprint(messages != nil)), nil)
+	suite.handler.On("Evaluate", mock.Anything, mock.AnythingOfType("string")).Return(times.evaluate, nil).Run(func(args mock.Arguments) {
+		if f, ok := frames[args.String(1)]; ok {
+			log(args.Get(0).(context.Context), f)
+		}
+	})
+	suite.handler.On("Screenshot", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, options browser.ScreenshotOptions) []byte {
+			return []byte(times.time(ctx) + "\n")
+		},
+		nil,
+	)
+	mockBrowser(suite.handler, nil)
+}
+
+// record records the page with the Omega.js protocol, and returns the times
+// of the written frames and what was printed meanwhile.
+func (suite *OmegaSuite) record(params RecordParams) ([]string, RecordStats, string, error) {
+	var output bytes.Buffer
+	params.Sink = NewOrderedWriter(&output, params.Workers * REORDER_FRAMES_PER_WORKER)
+	params.Protocol = true
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	suite.Require().NoError(err)
+	os.Stdout = w
+	printed := make(chan []byte)
+	go func() {
+		out, _ := ioutil.ReadAll(r)
+		printed <- out
+	}()
+	stats, err := record(context.Background(), params)
+	os.Stdout = stdout
+	w.Close()
+
+	times := strings.Fields(output.String())
+	return times, stats, string(<- printed), err
+}
+
+// goTos returns the times of the frames from start up to end.
+func goTos(params RecordParams, start, end int) []string {
+	times := make([]string, 0, end - start)
+	for f := start; f < end; f++ {
+		times = append(times, params.goTo(f))
+	}
+	return times
+}
+
+func (suite *OmegaSuite) TestParseCommand() {
+	command, ok := ParseCommand(`{"type":"command","action":"done","message":"Done"}`)
+	suite.True(ok)
+	suite.Equal(Command{Type: "command", Action: "done", Message: "Done"}, command)
+
+	command, ok = ParseCommand(`{"type":"message","message":"loaded"}`)
+	suite.True(ok)
+	suite.Equal("loaded", command.Message)
+
+	_, ok = ParseCommand(`rendering frame`)
+	suite.False(ok)
+	_, ok = ParseCommand(`{"level":"info"}`)
+	suite.False(ok)
+}
+
+func (suite *OmegaSuite) TestRecordProtocol() {
+	messages := map[int]string{
+		0: `{"type":"message","message":"loaded"}`,
+		10: `{"type":"command","action":"start"}`,
+		15: `{"type":"message","message":"halfway"}`,
+		20: `{"type":"command","action":"stop"}`,
+		40: `{"type":"message","message":"stopped"}`,
+	}
+	for _, workers := range []int{1, 3} {
+		suite.Run(fmt.Sprintf("should bound the recording with start and stop with %d workers", workers), func() {
+			params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: workers}
+			suite.setup(params, messages)
+			times, stats, printed, err := suite.record(params)
+			suite.NoError(err)
+			suite.Equal(11, stats.Frames)
+			suite.Equal(goTos(params, 10, 21), times)
+
+			// Messages should be printed once, in order, up to the stop
+			suite.Equal(1, strings.Count(printed, "loaded"))
+			suite.Equal(1, strings.Count(printed, "halfway"))
+			suite.Less(strings.Index(printed, "loaded"), strings.Index(printed, "halfway"))
+			suite.NotContains(printed, "stopped")
+
+			// The console listeners should be removed
+			suite.Len(suite.listeners, workers)
+			for _, listener := range suite.listeners {
+				suite.Error(listener.Err())
+			}
+		})
+	}
+
+	suite.Run("should stop scheduling frames after stop", func() {
+		params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: 1}
+		suite.setup(params, messages)
+		_, _, _, err := suite.record(params)
+		suite.NoError(err)
+		suite.handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, params.goTo(20))
+		suite.handler.AssertNotCalled(suite.T(), "Evaluate", mock.Anything, params.goTo(21))
+	})
+
+	suite.Run("should end the recording when done", func() {
+		params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: 4}
+		suite.setup(params, map[int]string{30: `{"type":"command","action":"done"}`})
+		times, stats, _, err := suite.record(params)
+		suite.NoError(err)
+		suite.Equal(31, stats.Frames)
+		suite.Equal(goTos(params, 0, 31), times)
+	})
+
+	suite.Run("should record the whole duration without commands", func() {
+		params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: 2}
+		suite.setup(params, map[int]string{})
+		times, stats, _, err := suite.record(params)
+		suite.NoError(err)
+		suite.Equal(60, stats.Frames)
+		suite.Equal(goTos(params, 0, 60), times)
+	})
+
+	suite.Run("should record pages without Omega.js whole", func() {
+		params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: 2}
+		suite.setup(params, nil)
+		times, stats, _, err := suite.record(params)
+		suite.NoError(err)
+		suite.Equal(60, stats.Frames)
+		suite.Len(times, 60)
+		// Only the first worker should have listened to the page
+		suite.Len(suite.listeners, 1)
+		suite.Error(suite.listeners[0].Err())
+	})
+
+	suite.Run("should reject Omega.screenshot", func() {
+		params := RecordParams{Duration: 1000, URL: "https://example.com", Workers: 2}
+		suite.setup(params, map[int]string{12: `{"type":"command","action":"screenshot"}`})
+		_, _, _, err := suite.record(params)
+
+		var frameError *FrameError
+		suite.True(errors.As(err, &frameError))
+		suite.Equal(12, frameError.Frame)
+		suite.Equal("omega", frameError.Op)
+	})
+}
+
+// Run the test suite
+func TestOmegaSuite(t *testing.T) {
+	suite.Run(t, new(OmegaSuite))
+}
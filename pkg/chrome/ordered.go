@@ -0,0 +1,111 @@
+package chrome
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// OrderedWriter is a FrameSink that writes frames to an io.Writer, like the
+// stdin of an encoder, strictly by frame index. Frames that arrive before
+// their turn are held in a reorder buffer of bounded capacity. Once the buffer
+// is full, writing a frame blocks until the frames before it are written, so
+// memory stays capped.
+type OrderedWriter struct {
+	writer io.Writer
+	// capacity is the number of frames ahead of the next one that can be
+	// buffered.
+	capacity int
+	mu sync.Mutex
+	cond *sync.Cond
+	// next is the index of the next frame to write.
+	next int
+	// pending holds the frames waiting for their turn.
+	pending map[int][]byte
+	err error
+}
+
+// NewOrderedWriter creates an OrderedWriter that can buffer up to capacity
+// frames. The capacity should be at least the number of concurrent writers.
+func NewOrderedWriter(writer io.Writer, capacity int) *OrderedWriter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	w := &OrderedWriter{
+		writer: writer,
+		capacity: capacity,
+		pending: make(map[int][]byte),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// WriteFrame buffers the frame and writes every consecutive frame that is
+// ready. It blocks while the frame is too far ahead of the next one.
+func (w *OrderedWriter) WriteFrame(index int, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Apply backpressure until the frame fits in the buffer
+	for w.err == nil && index >= w.next + w.capacity {
+		w.cond.Wait()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if _, ok := w.pending[index]; ok || index < w.next {
+		return fmt.Errorf("frame %d was already written", index)
+	}
+	w.pending[index] = data
+
+	// Flush every consecutive frame
+	defer w.cond.Broadcast()
+	for {
+		frame, ok := w.pending[w.next]
+		if !ok {
+			return nil
+		}
+		delete(w.pending, w.next)
+		if _, err := w.writer.Write(frame); err != nil {
+			w.err = err
+			return err
+		}
+		w.next++
+	}
+}
+
+// Abort makes every pending and future WriteFrame call fail with err, so
+// writers waiting for a frame that will never arrive are released.
+func (w *OrderedWriter) Abort(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.cond.Broadcast()
+}
+
+// Close fails if frames are still waiting for the ones before them. It
+// doesn't close the underlying io.Writer.
+func (w *OrderedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil && len(w.pending) > 0 {
+		w.err = fmt.Errorf("%d frames are waiting for frame %d", len(w.pending), w.next)
+	}
+	return w.err
+}
+
+// Err returns the first error of the writer.
+func (w *OrderedWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Buffered returns the number of frames waiting for their turn.
+func (w *OrderedWriter) Buffered() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
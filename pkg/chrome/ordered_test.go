@@ -0,0 +1,115 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type OrderedWriterSuite struct {
+	suite.Suite
+}
+
+func (suite *OrderedWriterSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+func (suite *OrderedWriterSuite) TestWriteFrame() {
+	suite.Run("should hold frames until the previous ones are written", func() {
+		var output bytes.Buffer
+		writer := NewOrderedWriter(&output, 4)
+		suite.NoError(writer.WriteFrame(2, []byte("2")))
+		suite.NoError(writer.WriteFrame(1, []byte("1")))
+		suite.Equal("", output.String())
+		suite.Equal(2, writer.Buffered())
+		suite.NoError(writer.WriteFrame(0, []byte("0")))
+		suite.Equal("012", output.String())
+		suite.Equal(0, writer.Buffered())
+	})
+
+	suite.Run("should block frames that don't fit in the buffer", func() {
+		var output bytes.Buffer
+		writer := NewOrderedWriter(&output, 2)
+		written := make(chan bool)
+		go func() {
+			suite.NoError(writer.WriteFrame(2, []byte("2")))
+			written <- true
+		}()
+		select {
+		case <- written:
+			suite.Fail("frame 2 should wait for frame 0")
+		case <- time.After(10 * time.Millisecond):
+		}
+		suite.NoError(writer.WriteFrame(0, []byte("0")))
+		suite.NoError(writer.WriteFrame(1, []byte("1")))
+		<- written
+		suite.Equal("012", output.String())
+	})
+
+	suite.Run("should reject frames written twice", func() {
+		writer := NewOrderedWriter(&bytes.Buffer{}, 2)
+		suite.NoError(writer.WriteFrame(0, []byte("0")))
+		suite.Error(writer.WriteFrame(0, []byte("0")))
+	})
+
+	suite.Run("should release the blocked frames when aborted", func() {
+		writer := NewOrderedWriter(&bytes.Buffer{}, 1)
+		aborted := errors.New("aborted")
+		written := make(chan error)
+		go func() {
+			written <- writer.WriteFrame(1, []byte("1"))
+		}()
+		writer.Abort(aborted)
+		suite.Equal(aborted, <- written)
+		suite.Equal(aborted, writer.WriteFrame(0, []byte("0")))
+	})
+}
+
+func (suite *OrderedWriterSuite) TestRecordOrder() {
+	// Screenshots take a random amount of time and contain the frame time
+	handler := &mbrowser.BrowserHandler{}
+	var times pageTimes
+	handler.On("Evaluate", mock.Anything, mock.AnythingOfType("string")).Return(times.evaluate, nil)
+	handler.On("Screenshot", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, options browser.ScreenshotOptions) []byte {
+			time.Sleep(time.Duration(rand.Intn(3000)) * time.Microsecond)
+			return []byte(fmt.Sprintf("%-24s\n", times.time(ctx)))
+		},
+		nil,
+	)
+	mockBrowser(handler, nil)
+
+	var output bytes.Buffer
+	params := RecordParams{
+		Duration: 1000,
+		URL     : "https://example.com",
+		Sink    : NewOrderedWriter(&output, 8 * REORDER_FRAMES_PER_WORKER),
+		Workers : 8,
+		Width : 1920,
+		Height: 1080,
+	}
+	_, err := record(context.Background(), params)
+	suite.NoError(err)
+
+	// Every frame should have been written in order
+	frames := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	suite.Equal(int(params.Duration * DEFAULT_FPS / 1000), len(frames))
+	for i, frame := range frames {
+		suite.Equal(goTo(i), strings.TrimSpace(frame))
+	}
+}
+
+// Run the test suite
+func TestOrderedWriterSuite(t *testing.T) {
+	suite.Run(t, new(OrderedWriterSuite))
+}
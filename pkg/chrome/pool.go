@@ -0,0 +1,87 @@
+package chrome
+
+import (
+	"context"
+	"sync"
+
+	"gux.codes/omega/pkg/browser"
+)
+
+// pooledContext is a browser context kept by a ContextPool.
+type pooledContext struct {
+	ctx context.Context
+	cancel context.CancelFunc
+}
+
+// ContextPool shares browser contexts between recordings, so consecutive
+// recordings don't have to start a new browser for each worker. Contexts are
+// reset to a blank page when they are returned, and the ones that used the
+// virtual time are released instead. A nil
+// ContextPool creates a new browser context every time and releases it when
+// it is returned.
+type ContextPool struct {
+	parent context.Context
+	mu sync.Mutex
+	free []pooledContext
+	// created counts the browser contexts created by the pool.
+	created int
+}
+
+// NewContextPool creates an empty pool whose browser contexts derive from
+// parent.
+func NewContextPool(parent context.Context) *ContextPool {
+	return &ContextPool{parent: parent}
+}
+
+// get returns a free browser context, or creates a new one.
+func (p *ContextPool) get(parent context.Context) (context.Context, context.CancelFunc) {
+	if p == nil {
+		return browser.Chrome.NewContext(parent)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.free); n > 0 {
+		c := p.free[n - 1]
+		p.free = p.free[:n - 1]
+		return c.ctx, c.cancel
+	}
+	p.created++
+	return browser.Chrome.NewContext(p.parent)
+}
+
+// put returns a browser context to the pool, resetting its page so the next
+// recording starts from a blank one. Contexts that ended, because they
+// crashed or were canceled, that can't be reset, or that aren't reusable, are
+// dropped.
+func (p *ContextPool) put(ctx context.Context, cancel context.CancelFunc, reusable bool) {
+	if p == nil || !reusable || ctx.Err() != nil {
+		cancel()
+		return
+	}
+	if err := browser.Chrome.Reset(ctx); err != nil {
+		cancel()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, pooledContext{ctx: ctx, cancel: cancel})
+}
+
+// Created returns the number of browser contexts created by the pool.
+func (p *ContextPool) Created() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.created
+}
+
+// Close releases every free browser context.
+func (p *ContextPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.free {
+		c.cancel()
+	}
+	p.free = nil
+}
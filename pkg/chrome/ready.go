@@ -0,0 +1,217 @@
+package chrome
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gux.codes/omega/pkg/browser"
+)
+
+// DEFAULT_READY_TIMEOUT is how long a ready condition can take when it
+// doesn't set a timeout.
+const DEFAULT_READY_TIMEOUT time.Duration = 30 * time.Second
+
+// NETWORK_IDLE_TIME is how long the page must go without pending requests to
+// be considered idle.
+const NETWORK_IDLE_TIME time.Duration = 500 * time.Millisecond
+
+// READY_POLL_INTERVAL is the time between evaluations of a condition.
+const READY_POLL_INTERVAL time.Duration = 50 * time.Millisecond
+
+// Kinds of ready conditions.
+const (
+	// NETWORK_IDLE waits until the page has no pending request.
+	NETWORK_IDLE string = "networkidle"
+	// FONTS waits until `document.fonts` finishes loading.
+	FONTS string = "fonts"
+	// JS waits until a JavaScript expression, like `Omega.isReady()`, is truthy.
+	// The value of the last statement is used when there are several.
+	JS string = "js"
+	// SELECTOR waits until an element matches a CSS selector.
+	SELECTOR string = "selector"
+)
+
+// ReadyCondition must hold before the frames of a page are captured.
+type ReadyCondition struct {
+	// Kind is one of NETWORK_IDLE, FONTS, JS or SELECTOR.
+	Kind string
+	// Value is the expression of JS conditions, or the CSS selector of
+	// SELECTOR ones.
+	Value string
+	// Timeout defaults to DEFAULT_READY_TIMEOUT.
+	Timeout time.Duration
+}
+
+// ConditionError is returned when a ready condition doesn't hold in time.
+type ConditionError struct {
+	// Condition is the failing condition, like js:Omega.isReady().
+	Condition string
+	Err error
+}
+
+// Error returns the error message, including the condition.
+func (e *ConditionError) Error() string {
+	return fmt.Sprintf("condition %s: %s", e.Condition, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConditionError) Unwrap() error {
+	return e.Err
+}
+
+// ParseReadyCondition parses a condition written as networkidle, fonts,
+// js:EXPRESSION or selector:SELECTOR.
+func ParseReadyCondition(spec string, timeout time.Duration) (ReadyCondition, error) {
+	spec = strings.TrimSpace(spec)
+	kind, value := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, value = spec[:i], strings.TrimSpace(spec[i + 1:])
+	}
+	condition := ReadyCondition{Kind: kind, Value: value, Timeout: timeout}
+	switch kind {
+	case NETWORK_IDLE, FONTS:
+		if value != "" {
+			return condition, fmt.Errorf("the %s condition takes no value", kind)
+		}
+	case JS, SELECTOR:
+		if value == "" {
+			return condition, fmt.Errorf("the %s condition needs a value, like %s:VALUE", kind, kind)
+		}
+	default:
+		return condition, fmt.Errorf("unknown ready condition %q, use networkidle, fonts, js:EXPRESSION or selector:SELECTOR", spec)
+	}
+	return condition, nil
+}
+
+// ParseReadyConditions parses a list of conditions separated by semicolons,
+// like `fonts;js:Omega.isReady()`. A semicolon only starts a new condition if
+// one follows it, so JavaScript expressions can contain semicolons, like
+// `js:const ready = Omega.isReady(); ready`.
+func ParseReadyConditions(specs string, timeout time.Duration) ([]ReadyCondition, error) {
+	var conditions []ReadyCondition
+	for _, spec := range splitReadyConditions(specs) {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		condition, err := ParseReadyCondition(spec, timeout)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// splitReadyConditions splits the conditions at the semicolons followed by a
+// condition, or by nothing else.
+func splitReadyConditions(specs string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(specs); i++ {
+		if specs[i] == ';' && startsReadyCondition(specs[i + 1:]) {
+			parts = append(parts, specs[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, specs[start:])
+}
+
+// startsReadyCondition reports whether the text starts with a condition.
+func startsReadyCondition(text string) bool {
+	text = strings.TrimLeft(text, " ")
+	if text == "" {
+		return true
+	}
+	for _, kind := range []string{NETWORK_IDLE, FONTS} {
+		if rest := strings.TrimPrefix(text, kind); rest != text {
+			if rest = strings.TrimLeft(rest, " "); rest == "" || rest[0] == ';' {
+				return true
+			}
+		}
+	}
+	return strings.HasPrefix(text, JS + ":") || strings.HasPrefix(text, SELECTOR + ":")
+}
+
+// String returns the condition as it is written.
+func (c ReadyCondition) String() string {
+	if c.Value == "" {
+		return c.Kind
+	}
+	return c.Kind + ":" + c.Value
+}
+
+// timeout returns how long the condition can take.
+func (c ReadyCondition) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DEFAULT_READY_TIMEOUT
+	}
+	return c.Timeout
+}
+
+// script returns the expression polled until it is true. JS conditions go
+// through eval, since they can have several statements.
+func (c ReadyCondition) script() string {
+	switch c.Kind {
+	case FONTS:
+		return `document.fonts.status === "loaded"`
+	case SELECTOR:
+		selector, _ := json.Marshal(c.Value)
+		return fmt.Sprintf(`document.querySelector(%s) !== null`, selector)
+	}
+	value, _ := json.Marshal(c.Value)
+	return fmt.Sprintf(`Boolean(eval(%s))`, value)
+}
+
+// Wait blocks until the condition holds on the page of the browser context,
+// or fails with a *ConditionError once its timeout passes.
+func (c ReadyCondition) Wait(parent context.Context) error {
+	ctx, cancel := context.WithTimeout(parent, c.timeout())
+	defer cancel()
+
+	var err error
+	if c.Kind == NETWORK_IDLE {
+		err = browser.Chrome.WaitNetworkIdle(ctx, NETWORK_IDLE_TIME)
+	} else {
+		err = poll(ctx, c.script())
+	}
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+		err = fmt.Errorf("not ready after %s", c.timeout())
+	}
+	return &ConditionError{Condition: c.String(), Err: err}
+}
+
+// poll evaluates the script until it returns true.
+func poll(ctx context.Context, script string) error {
+	for {
+		res, err := browser.Chrome.Evaluate(ctx, script)
+		if err != nil {
+			return err
+		}
+		var ready bool
+		if json.Unmarshal(res, &ready) == nil && ready {
+			return nil
+		}
+		select {
+		case <- ctx.Done():
+			return ctx.Err()
+		case <- time.After(READY_POLL_INTERVAL):
+		}
+	}
+}
+
+// waitReady waits for every condition, in order.
+func waitReady(ctx context.Context, conditions []ReadyCondition) error {
+	for _, condition := range conditions {
+		if err := condition.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
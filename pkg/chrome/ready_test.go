@@ -0,0 +1,160 @@
+package chrome
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type ReadySuite struct {
+	suite.Suite
+	handler *mbrowser.BrowserHandler
+}
+
+// setup mocks a browser whose page is ready after the given number of polls
+// of the expression.
+func (suite *ReadySuite) setup(expression string, polls int) {
+	suite.handler = &mbrowser.BrowserHandler{}
+	var mu sync.Mutex
+	calls := 0
+	script := ReadyCondition{Kind: JS, Value: expression}.script()
+	suite.handler.On("Evaluate", mock.Anything, script).Return(
+		func(ctx context.Context, script string) []byte {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls >= polls {
+				return []byte("true")
+			}
+			return []byte("false")
+		},
+		nil,
+	)
+	mockBrowser(suite.handler, []byte("frame"))
+}
+
+func (suite *ReadySuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+func (suite *ReadySuite) TestParseReadyConditions() {
+	conditions, err := ParseReadyConditions("networkidle; fonts;js:Omega.isReady();selector:#chart .bar", time.Second)
+	suite.NoError(err)
+	suite.Equal([]ReadyCondition{
+		{Kind: NETWORK_IDLE, Timeout: time.Second},
+		{Kind: FONTS, Timeout: time.Second},
+		{Kind: JS, Value: "Omega.isReady()", Timeout: time.Second},
+		{Kind: SELECTOR, Value: "#chart .bar", Timeout: time.Second},
+	}, conditions)
+	suite.Equal("js:Omega.isReady()", conditions[2].String())
+
+	// Semicolons that don't start a condition belong to the expression
+	conditions, err = ParseReadyConditions("js:a(); b();fonts ;  js:c();", time.Second)
+	suite.NoError(err)
+	suite.Equal([]ReadyCondition{
+		{Kind: JS, Value: "a(); b()", Timeout: time.Second},
+		{Kind: FONTS, Timeout: time.Second},
+		{Kind: JS, Value: "c()", Timeout: time.Second},
+	}, conditions)
+	conditions, err = ParseReadyConditions("js:let fonts = 1; fonts > 0", time.Second)
+	suite.NoError(err)
+	suite.Equal("let fonts = 1; fonts > 0", conditions[0].Value)
+
+	conditions, err = ParseReadyConditions("", time.Second)
+	suite.NoError(err)
+	suite.Empty(conditions)
+
+	for _, value := range []string{"load", "js:", "selector", "fonts:all"} {
+		_, err := ParseReadyConditions(value, time.Second)
+		suite.Error(err, value)
+	}
+}
+
+func (suite *ReadySuite) TestScript() {
+	suite.Equal(`document.querySelector("#chart \"a\"") !== null`, ReadyCondition{Kind: SELECTOR, Value: `#chart "a"`}.script())
+	suite.Equal(`document.fonts.status === "loaded"`, ReadyCondition{Kind: FONTS}.script())
+	suite.Equal(`Boolean(eval("Omega.isReady()"))`, ReadyCondition{Kind: JS, Value: "Omega.isReady()"}.script())
+	suite.Equal(`Boolean(eval("const ready = Omega.isReady(); ready === \"yes\""))`, ReadyCondition{Kind: JS, Value: `const ready = Omega.isReady(); ready === "yes"`}.script())
+}
+
+func (suite *ReadySuite) TestWait() {
+	suite.Run("should poll the condition until it holds", func() {
+		suite.setup("Omega.isReady()", 3)
+		params := RecordParams{
+			Duration: 100,
+			Workers : 1,
+			Ready   : []ReadyCondition{{Kind: JS, Value: "Omega.isReady()", Timeout: time.Second}},
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(6, stats.Frames)
+		suite.handler.AssertNumberOfCalls(suite.T(), "Screenshot", 6)
+	})
+
+	suite.Run("should evaluate the statements of the condition", func() {
+		suite.setup("const ready = Omega.isReady(); ready", 2)
+		condition := ReadyCondition{Kind: JS, Value: "const ready = Omega.isReady(); ready", Timeout: time.Second}
+		suite.NoError(condition.Wait(context.Background()))
+		suite.handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, `Boolean(eval("const ready = Omega.isReady(); ready"))`)
+		suite.handler.AssertNumberOfCalls(suite.T(), "Evaluate", 2)
+	})
+
+	suite.Run("should name the condition that timed out", func() {
+		suite.setup("Omega.isReady()", 1000)
+		params := RecordParams{
+			Duration: 100,
+			Workers : 1,
+			Ready   : []ReadyCondition{{Kind: JS, Value: "Omega.isReady()", Timeout: 120 * time.Millisecond}},
+			Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+		}
+		_, err := record(context.Background(), params)
+		var conditionError *ConditionError
+		suite.True(errors.As(err, &conditionError))
+		suite.Equal("js:Omega.isReady()", conditionError.Condition)
+		suite.Contains(err.Error(), "not ready after 120ms")
+		suite.handler.AssertNotCalled(suite.T(), "Screenshot", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("should wait for the network to be idle", func() {
+		suite.setup("Omega.isReady()", 1)
+		suite.handler.On("WaitNetworkIdle", mock.Anything, NETWORK_IDLE_TIME).Return(nil)
+		err := waitReady(context.Background(), []ReadyCondition{{Kind: NETWORK_IDLE}})
+		suite.NoError(err)
+		suite.handler.AssertNumberOfCalls(suite.T(), "WaitNetworkIdle", 1)
+	})
+}
+
+func (suite *ReadySuite) TestReadyEachFrame() {
+	suite.setup("Omega.isReady()", 1)
+	params := RecordParams{
+		Duration: 100,
+		Workers : 1,
+		Ready   : []ReadyCondition{{Kind: JS, Value: "Omega.isReady()"}},
+		ReadyEachFrame: true,
+		Sink    : &mapFrameSink{frames: make(map[int][]byte)},
+	}
+	_, err := record(context.Background(), params)
+	suite.NoError(err)
+	suite.handler.AssertNumberOfCalls(suite.T(), "Screenshot", 6)
+	// Once after the page loads, and once per frame
+	count := 0
+	for _, call := range suite.handler.Calls {
+		if call.Method == "Evaluate" && call.Arguments.Get(1) == `Boolean(eval("Omega.isReady()"))` {
+			count++
+		}
+	}
+	suite.Equal(7, count)
+}
+
+// Run the test suite
+func TestReadySuite(t *testing.T) {
+	suite.Run(t, new(ReadySuite))
+}
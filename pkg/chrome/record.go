@@ -2,14 +2,20 @@ package chrome
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"math"
+	"net/url"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/cheggaaa/pb"
+	"github.com/gin-gonic/gin"
 	"gux.codes/omega/pkg/browser"
 	"gux.codes/omega/pkg/utils"
 )
@@ -18,177 +24,644 @@ import (
 type RecordParams struct {
 	// Duration to recording.
 	Duration float64
+	// FPS is the frame rate of the recording. Fractional rates, like 29.97,
+	// are supported. Defaults to DEFAULT_FPS.
+	FPS float64
 	// URL specifies the URL to be recorded.
 	URL string
-	// Interface used to write the frames to disk.
-	Writer utils.Writer
+	// File is a local HTML file to record, served with the files next to it.
+	File string
+	// Dir is a local directory to record, served as static files. Its
+	// index.html is recorded.
+	Dir string
+	// EntryPoint of an esbuild project to record. The project is built once
+	// and served like `omega chrome dev` does, with timeweb.js injected.
+	EntryPoint string
+	// Sink receives the frames by index. Record and render build it from
+	// the Preset, Output, FramesDir, Zip and NoVideo params.
+	Sink FrameSink
+	// FramesDir is a directory where every frame is saved as a PNG file.
+	FramesDir string
+	// Zip is the path of a zip archive where every frame is saved as a PNG
+	// file.
+	Zip string
+	// NoVideo skips the ffmpeg encoding. Requires FramesDir or Zip.
+	NoVideo bool
+	// StartFrame is the first frame to record.
+	StartFrame int
+	// EndFrame is the frame right after the last one to record. Defaults to
+	// the end of the Duration. Setting a range ignores the Omega.js commands.
+	EndFrame int
 	// Workers used for the recording.
 	Workers int
+	// Retries is the number of times a frame is captured again after an
+	// error.
+	Retries int
+	// Output is the path of the encoded recording. Defaults to
+	// /tmp/out.{{ extension }}.
+	Output string
+	// Preset is the name of the ffmpeg container and codec preset.
+	Preset string
+	// FFmpegArgs are extra ffmpeg output arguments.
+	FFmpegArgs []string
 	// Viewport width.
 	Width int64
 	// Viewport height
 	Height int64
+	// DeviceScaleFactor renders the Width by Height layout with more device
+	// pixels, like 2 to record a 1920x1080 layout in 4K. Defaults to 1, or to
+	// the ratio between the output size and the viewport.
+	DeviceScaleFactor float64
+	// OutputWidth is the width of the captured frames. It sets the device
+	// scale factor, so the layout keeps the Width of the viewport.
+	OutputWidth int64
+	// OutputHeight is the height of the captured frames, see OutputWidth.
+	OutputHeight int64
+	// Time is the time mode of the recording: timeweb, which requires the page
+	// to load timeweb.js, or virtual. Defaults to timeweb.
+	Time string
+	// Clock controls the time of the pages. Takes precedence over Time.
+	Clock TimeController
+	// Ready lists the conditions that must hold after the page loads, before
+	// its frames are captured.
+	Ready []ReadyCondition
+	// ReadyEachFrame also waits for the Ready conditions before each frame.
+	ReadyEachFrame bool
+	// Selector captures the region of the first element that matches it,
+	// measured once after the page loads.
+	Selector string
+	// Clip captures a region of the page, in CSS pixels. The size of the
+	// recording follows the region, rounded to even device pixels.
+	Clip *browser.Clip
+	// Format of the captured frames: png, jpeg or webp. Defaults to png.
+	Format string
+	// Quality of the jpeg and webp frames, from 0 to 100.
+	Quality int64
+	// Draft trades quality for speed: jpeg frames at half the resolution,
+	// encoded with DRAFT_PRESET.
+	Draft bool
+	// MotionBlur averages several sub-frames into each frame.
+	MotionBlur MotionBlur
+	// Supersample captures the frames at Supersample times their resolution
+	// and downscales them, smoothing thin lines and text.
+	Supersample int
+	// Transparent records the page over a transparent background. It requires
+	// a preset that keeps the alpha channel.
+	Transparent bool
+	// Protocol listens to the Omega.js commands of the page to find the
+	// frames to record. Duration becomes the maximum length of the recording.
+	Protocol bool
+	// Data is passed to the templates along with the recording params. See
+	// LoadData.
+	Data interface{}
+	// Pool provides the browser contexts of the workers. Each worker creates
+	// its own browser context if it is nil.
+	Pool *ContextPool
 }
 
-// FPS represents the fixed FPS to record an animation.
-const FPS float64 = 60.0
-// FRAME_DURATION represent the interval between frames when animating at
-// 60 fps, which is when `requestAnimationFrame` should update.
-const FRAME_DURATION float64 = 1000.0 / FPS
+// DEFAULT_FPS is the frame rate used when RecordParams doesn't set one.
+const DEFAULT_FPS float64 = 60.0
 
-type StdinWriter struct {
-	stdin io.WriteCloser
+// DEFAULT_RETRIES is the number of times a frame is captured again after an
+// error, unless RecordParams sets it.
+const DEFAULT_RETRIES int = 2
+
+// DRAFT_QUALITY is the jpeg quality of draft recordings.
+const DRAFT_QUALITY int64 = 80
+
+// REORDER_FRAMES_PER_WORKER is the number of out of order frames buffered in
+// memory for each worker. The frames further ahead are spooled to disk.
+const REORDER_FRAMES_PER_WORKER int = 2
+
+// fps returns the frame rate of the recording.
+func (params RecordParams) fps() float64 {
+	if params.FPS <= 0 {
+		return DEFAULT_FPS
+	}
+	return params.FPS
 }
 
-// WriteFile exposes the ioutil.WriteFile function
-func (w StdinWriter) WriteFile(filename string, data []byte, perm os.FileMode) error {
-	_, err := w.stdin.Write(data)
+// frameDuration returns the interval between frames in ms, which is when
+// `requestAnimationFrame` should update. It uses the same fraction as the
+// frame rate passed to ffmpeg, so the frames of NTSC rates, like 29.97, are
+// 1001/30 ms apart.
+func (params RecordParams) frameDuration() float64 {
+	num, den := frameRateFraction(params.fps())
+	return 1000 * den / num
+}
 
-	return err
+// scale returns the device scale factor of the recording, or zero for the
+// default one. It fails if the output size doesn't keep the aspect ratio of
+// the viewport, or disagrees with DeviceScaleFactor.
+func (params RecordParams) scale() (float64, error) {
+	scale := params.DeviceScaleFactor
+	if scale < 0 {
+		return 0, fmt.Errorf("invalid device scale factor %g", scale)
+	}
+	for _, size := range [][2]int64{{params.OutputWidth, params.Width}, {params.OutputHeight, params.Height}} {
+		output, layout := size[0], size[1]
+		if output <= 0 || layout <= 0 {
+			continue
+		}
+		ratio := float64(output) / float64(layout)
+		if scale <= 0 {
+			scale = ratio
+		}
+		if math.Round(float64(layout) * scale) != float64(output) {
+			return 0, fmt.Errorf("an output of %dx%d can't be captured from a %dx%d viewport", params.OutputWidth, params.OutputHeight, params.Width, params.Height)
+		}
+	}
+	return scale, nil
 }
 
-// Record starts the process of recording a Chrome animation.
-func Record(params RecordParams) error {
-	// Create a canceable context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// viewport returns the browser viewport of the recording. Supersampled
+// recordings multiply its scale.
+func (params RecordParams) viewport() browser.Viewport {
+	scale, _ := params.scale()
+	if params.Supersample > 1 {
+		if scale == 0 {
+			scale = 1
+		}
+		scale *= float64(params.Supersample)
+	}
+	return browser.Viewport{
+		Width: params.Width,
+		Height: params.Height,
+		Transparent: params.Transparent,
+		DeviceScaleFactor: scale,
+	}
+}
+
+// page returns the URL of the page to record from the URL, File, Dir or
+// EntryPoint params, and the directory the web server should serve, if any.
+// It defaults to the handler of the web server.
+func (params RecordParams) page(port int) (string, string, error) {
+	set := 0
+	for _, source := range []string{params.URL, params.File, params.Dir, params.EntryPoint} {
+		if source != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", "", errors.New("only one of URL, File, Dir or EntryPoint can be recorded")
+	}
+
+	project := fmt.Sprintf("http://localhost:%d/project/", port)
+	switch {
+	case params.URL != "":
+		return params.URL, "", nil
+	case params.File != "":
+		path, err := filepath.Abs(params.File)
+		if err != nil {
+			return "", "", err
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return "", "", fmt.Errorf("can't find a file at: %s", params.File)
+		}
+		return project + url.PathEscape(filepath.Base(path)), filepath.Dir(path), nil
+	case params.Dir != "":
+		path, err := filepath.Abs(params.Dir)
+		if err != nil {
+			return "", "", err
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return "", "", fmt.Errorf("can't find a directory at: %s", params.Dir)
+		}
+		return project, path, nil
+	case params.EntryPoint != "" && params.Time == VIRTUAL_TIME:
+		return fmt.Sprintf("http://localhost:%d/dev", port), "", nil
+	case params.EntryPoint != "":
+		return fmt.Sprintf("http://localhost:%d/dev?timeweb=true", port), "", nil
+	}
+	return fmt.Sprintf("http://localhost:%d/handler", port), "", nil
+}
+
+// templateParams returns the params passed to the templates of the web
+// server.
+func (params RecordParams) templateParams() gin.H {
+	return gin.H{
+		"width": params.Width,
+		"height": params.Height,
+		"fps": params.fps(),
+		"duration": params.Duration,
+		"data": params.Data,
+	}
+}
+
+// screenshotOptions returns how the browser encodes the captured frames.
+func (params RecordParams) screenshotOptions() browser.ScreenshotOptions {
+	options := browser.ScreenshotOptions{Format: params.Format, Quality: params.Quality}
+	if params.Clip != nil {
+		scale, _ := params.scale()
+		clip := evenClip(*params.Clip, scale)
+		options.Clip = &clip
+	}
+	return options
+}
+
+// frameFormat returns the format of the frames written to the sinks. Frames
+// processed in Go, by the supersampling or the motion blur, are PNG images.
+func (params RecordParams) frameFormat() string {
+	if params.Format == "" || params.Supersample > 1 || params.MotionBlur.enabled() {
+		return browser.FormatPNG
+	}
+	return params.Format
+}
 
-	// Setup a Ctrl+C handler
+// checkFormat fails if the capture format is unknown or can't be used with the
+// rest of the params.
+func (params RecordParams) checkFormat() error {
+	switch params.Format {
+	case "", browser.FormatPNG:
+		return nil
+	case browser.FormatJPEG:
+		if params.Transparent {
+			return errors.New("jpeg frames can't be transparent, use png or webp")
+		}
+	case browser.FormatWebP:
+		if params.Supersample > 1 || params.MotionBlur.enabled() {
+			return errors.New("webp frames can't be supersampled or blurred, use png or jpeg")
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use png, jpeg or webp", params.Format)
+	}
+	if params.Quality < 0 || params.Quality > 100 {
+		return fmt.Errorf("invalid quality %d, use a value from 0 to 100", params.Quality)
+	}
+	return nil
+}
+
+// draft returns the params of a quick preview: jpeg frames at half the
+// resolution, encoded with DRAFT_PRESET unless another preset was chosen.
+func (params RecordParams) draft() RecordParams {
+	if params.Format == "" || params.Format == browser.FormatPNG {
+		params.Format = browser.FormatJPEG
+	}
+	if params.Quality == 0 {
+		params.Quality = DRAFT_QUALITY
+	}
+	scale, _ := params.scale()
+	if scale == 0 {
+		scale = 1
+	}
+	params.DeviceScaleFactor = scale / 2
+	params.OutputWidth, params.OutputHeight = 0, 0
+	if params.Preset == "" || params.Preset == DEFAULT_PRESET {
+		params.Preset = DRAFT_PRESET
+	}
+	return params
+}
+
+// frameTime returns the time of the frame in ms.
+func (params RecordParams) frameTime(frame int) float64 {
+	return float64(frame) * params.frameDuration()
+}
+
+// goTo returns the timeweb.js script that moves the time of the page to the
+// frame.
+func (params RecordParams) goTo(frame int) string {
+	return timewebScript(params.frameTime(frame))
+}
+
+// clock returns the TimeController of the recording.
+func (params RecordParams) clock() TimeController {
+	if params.Clock != nil {
+		return params.Clock
+	}
+	controller, err := TimeControllerByName(params.Time)
+	if err != nil {
+		return TimewebController{}
+	}
+	return controller
+}
+
+// Frame returns the frame shown at the time, in ms.
+func (params RecordParams) Frame(ms float64) int {
+	num, den := frameRateFraction(params.fps())
+	return int(math.Floor(ms * num / (den * 1000) + 1e-9))
+}
+
+// frames returns the amount of frames to record.
+func (params RecordParams) frames() int {
+	num, den := frameRateFraction(params.fps())
+	return int(math.Ceil(params.Duration * num / (den * 1000)))
+}
+
+// interruptContext returns a context that is canceled when Ctrl+C is pressed.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	go func(){
-		<- signals
-		fmt.Println("\r- Ctrl+C pressed in Terminal")
-		cancel()
-		os.Exit(0)
+		defer signal.Stop(signals)
+		select {
+		case <- signals:
+			fmt.Println("\r- Ctrl+C pressed in Terminal")
+			cancel()
+		case <- ctx.Done():
+		}
 	}()
+	return ctx, cancel
+}
 
-	// Start the web server on a different goroutine
-	webServerOptions := NewWebServerOptions()
-	go Serve(webServerOptions)
+// Record starts the process of recording a Chrome animation. If a frame can't
+// be recorded, the sinks are aborted, ffmpeg is stopped, its output removed,
+// and a *FrameError is returned.
+func Record(params RecordParams) error {
+	// Create a context canceled by Ctrl+C
+	ctx, cancel := interruptContext()
+	defer cancel()
 
-	// Create the ffmpeg command
-	cmd := exec.Command(`ffmpeg`,
-		`-y`,
-		`-framerate`, `60`,
-		`-i`, `pipe:0`,
-		`-c:v`, `libx264`,
-		`-pix_fmt`, `yuv420p`,
-  	`-r`, `60`,
-		`/tmp/out.mp4`,
-	)
-
-	// Pipe cmd stderr and stdout to the console
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-
-	// Open stdin pipe
-	stdin, err := cmd.StdinPipe()
+	// Serve the page to record
+	params, err := params.serve()
 	if err != nil {
 		return err
 	}
 
-	// Override the provided RecordParams
-	params.Writer = StdinWriter{stdin}
-	params.URL = fmt.Sprintf("http://localhost:%d/handler", webServerOptions.Port)
-
-	// Start the ffmpeg command
-	if err := cmd.Start(); err != nil {
+	// Record and encode the page
+	stats, output, err := render(ctx, params)
+	if err != nil {
 		return err
 	}
+	utils.Info(stats.String())
+	utils.Success("Recording saved at " + output)
+
+	return nil
+}
+
+// serve chooses the page to record and starts the web server on a different
+// goroutine. It returns the params with the URL of the page.
+func (params RecordParams) serve() (RecordParams, error) {
+	webServerOptions := NewWebServerOptions()
+	pageURL, root, err := params.page(webServerOptions.Port)
+	if err != nil {
+		return params, err
+	}
+	webServerOptions.Root = root
+	webServerOptions.Params = params.templateParams()
+	if params.EntryPoint != "" {
+		utils.Info("Building " + params.EntryPoint + "...")
+		if err := buildProject(params.EntryPoint); err != nil {
+			return params, err
+		}
+	}
+	go Serve(webServerOptions)
+	params.URL = pageURL
+	return params, nil
+}
+
+// render records the page at params.URL and writes its frames to the sinks
+// chosen by the params. It returns the paths of the outputs.
+func render(ctx context.Context, params RecordParams) (RecordStats, string, error) {
+	var stats RecordStats
+	if params.Draft {
+		params = params.draft()
+	}
+
+	// Create the sinks
+	sink, output, err := params.sink()
+	if err != nil {
+		return stats, output, err
+	}
+	sink = params.process(sink)
+	params.Sink = sink
 
 	// Start the recording process
-	if err := record(ctx, params); err != nil {
-		return err
+	stats, err = record(ctx, params)
+	if err != nil {
+		// Release the sinks and remove the truncated outputs
+		abort(sink, err)
+		return stats, output, err
 	}
 
-	// Close stdin, or ffmpeg will wait forever
-	if err := stdin.Close(); err != nil {
-		return err
+	// Flush the sinks, waiting until ffmpeg finishes
+	return stats, output, sink.Close()
+}
+
+// process wraps the sink with the processing of the captured frames: the
+// downscaling of supersampled recordings and the alpha check of transparent
+// ones.
+func (params RecordParams) process(sink FrameSink) FrameSink {
+	if params.Supersample > 1 {
+		sink = NewDownscaler(sink, params.Supersample, params.Workers * REORDER_FRAMES_PER_WORKER)
 	}
+	if params.Transparent {
+		sink = &alphaWriter{FrameSink: sink}
+	}
+	return sink
+}
 
-	// Wait until ffmpeg finishes
-	if err := cmd.Wait(); err != nil {
-		return err
+// preset returns the preset that encodes the video. Transparent recordings
+// switch to ALPHA_PRESET if the preset drops the alpha channel.
+func (params RecordParams) preset() (Preset, error) {
+	preset, err := PresetByName(params.Preset)
+	if err != nil {
+		return preset, err
+	}
+	if params.Transparent && !preset.Alpha {
+		utils.Info(fmt.Sprintf("The %s preset drops the alpha channel, using %s instead", preset.Name, ALPHA_PRESET))
+		preset = Presets[ALPHA_PRESET]
 	}
+	return preset, nil
+}
 
-	return nil
+// sink creates the sinks of the recording: an ffmpeg process that encodes the
+// Output with the Preset, unless NoVideo is set, the FramesDir directory and
+// the Zip archive. It returns their paths separated by commas.
+func (params RecordParams) sink() (FrameSink, string, error) {
+	var sinks []FrameSink
+	var outputs []string
+	fail := func(err error) (FrameSink, string, error) {
+		for _, sink := range sinks {
+			abort(sink, err)
+		}
+		return nil, strings.Join(outputs, ", "), err
+	}
+
+	if params.FramesDir != "" {
+		if err := os.MkdirAll(params.FramesDir, 0755); err != nil {
+			return fail(err)
+		}
+		sink := NewDirectorySink(params.FramesDir, utils.FWriter)
+		sink.Extension = FormatExtension(params.frameFormat())
+		sinks = append(sinks, sink)
+		outputs = append(outputs, params.FramesDir)
+	}
+	if params.Zip != "" {
+		if err := os.MkdirAll(filepath.Dir(params.Zip), 0755); err != nil {
+			return fail(err)
+		}
+		sink, err := NewZipSink(params.Zip)
+		if err != nil {
+			return fail(err)
+		}
+		sink.Extension = FormatExtension(params.frameFormat())
+		sinks = append(sinks, sink)
+		outputs = append(outputs, params.Zip)
+	}
+	if !params.NoVideo {
+		preset, err := params.preset()
+		if err != nil {
+			return fail(err)
+		}
+		// Frames must reach ffmpeg in order
+		sink, err := NewFFmpegSink(preset, params.fps(), params.frameFormat(), params.Output, params.FFmpegArgs, params.Workers * REORDER_FRAMES_PER_WORKER)
+		if err != nil {
+			return fail(err)
+		}
+		sinks = append(sinks, sink)
+		outputs = append(outputs, sink.Output)
+	}
+
+	switch len(sinks) {
+	case 0:
+		return nil, "", errors.New("there is nothing to write the frames to, set a frames directory or a zip archive")
+	case 1:
+		return sinks[0], outputs[0], nil
+	}
+	return NewTeeSink(sinks...), strings.Join(outputs, ", "), nil
 }
 
-func record(parent context.Context, params RecordParams) error {
-	// Store all the available workers
-	availableWorkers := params.Workers
+// record records the frames of the animation with a browser context per
+// worker. The first worker error cancels the recording and is returned.
+func record(parent context.Context, params RecordParams) (RecordStats, error) {
+	var stats RecordStats
+	start := time.Now()
+
+	// Check the size and format of the frames
+	if _, err := params.scale(); err != nil {
+		return stats, err
+	}
+	if err := params.checkFormat(); err != nil {
+		return stats, err
+	}
+	if _, err := TimeControllerByName(params.Time); err != nil && params.Clock == nil {
+		return stats, err
+	}
+	if params.Selector != "" && params.Clip != nil {
+		return stats, errors.New("only one of Selector or Clip can be captured")
+	}
+
+	// Choose where to write the frames
+	frames := params.Sink
+	if frames == nil {
+		return stats, errors.New("there is no sink to write the frames to")
+	}
 
-	// Calculate the amount of frames ro record.
-	framesToRecord  := params.Duration * FPS / 1000
+	// Calculate the range of frames to record.
+	first, last := 0, params.frames()
+	var omega *protocol
+	switch {
+	case params.StartFrame > 0 || params.EndFrame > 0:
+		// A range ignores the Omega.js commands, so the workers seek straight
+		// to it
+		first = params.StartFrame
+		if params.EndFrame > 0 {
+			last = params.EndFrame
+		}
+		if first < 0 || first >= last {
+			return stats, fmt.Errorf("the frame range %d-%d is empty", first, last)
+		}
+	case params.Protocol:
+		omega = newProtocol(last)
+	}
+	framesToRecord := last - first
 
-	// Create a channel to communicate when a goroutine is ready to do more work
-	// providing an appropiate context.
-	routineReady := make(chan context.Context)
+	// There is no point in having more workers than frames
+	workers := params.Workers
+	if workers > framesToRecord {
+		workers = framesToRecord
+	}
+	if workers < 1 {
+		workers = 1
+	}
 
-	// Create a map that holds the frames that each browser has last processed
-	previousFrames := make(map[context.Context]float64)
+	// Split the frames in a contiguous range per worker
+	scheduler := newScheduler(first, last, workers)
 
-	// Instantiate the progress bar.
-	bar := pb.StartNew(int(framesToRecord))
-
-	// Run a goroutine to take a screenshot of each frame.
-	for f := 0.0; f < framesToRecord; f++ {
-		var ctx context.Context
-		ctx = nil
-		// Check if there are no more routines available
-		if availableWorkers == 0 {
-			// Wait until a new routine is ready
-			ctx = <- routineReady
-			// Increase the number of available routines
-			availableWorkers += 1
-		}
-		// Decrease the number of available routines
-		availableWorkers -= 1
-		// If no context is defined, we create a new one.
-		if ctx == nil {
-			var cancel context.CancelFunc
-			ctx, cancel = browser.Chrome.NewContext(parent)
-			if err := browser.Chrome.Navigate(ctx, params.URL, params.Width, params.Height); err != nil {
-				return err
+	// Open a browser context per worker
+	pool := make([]*worker, 0, workers)
+	defer func() {
+		for _, w := range pool {
+			w.close()
+		}
+	}()
+	for id := 0; id < workers; id++ {
+		w, err := newWorker(parent, id, params, omega, scheduler.done)
+		if err != nil {
+			return stats, fmt.Errorf("worker %d: %w", id, err)
+		}
+		pool = append(pool, w)
+		// Pages without Omega.js can't bound the recording
+		if id == 0 && omega != nil {
+			present, err := omegaPresent(w.ctx)
+			if err != nil {
+				return stats, fmt.Errorf("omega: %w", err)
 			}
-			defer cancel()
-		}
-		// Launch a new routine to record the current frame
-		go func(ctx context.Context, pf, f float64) {
-			// Windup clock
-			for i := pf + 1; i <= f; i++ {
-				script := fmt.Sprintf("timeweb.goTo(%.3f)", float64(i) * FRAME_DURATION)
-				if _, err := browser.Chrome.Evaluate(ctx, script); err != nil {
-					panic(err)
-				}
+			if !present {
+				omega, w.protocol = nil, nil
 			}
-			// Take screenshot
-			frame, err := browser.Chrome.Screenshot(ctx)
+		}
+		// Measure the element once, on the first browser context
+		if id == 0 && params.Selector != "" {
+			clip, err := resolveClip(w.ctx, params.Selector)
 			if err != nil {
-				panic(err)
+				return stats, fmt.Errorf("selector: %w", err)
 			}
-			// Store screenshot
-			if err := params.Writer.WriteFile(fmt.Sprintf("/tmp/%06.0f.png", f), frame, 0644); err != nil {
-				panic(err)
+			params.Clip = clip
+			w.params.Clip = clip
+		}
+	}
+
+	// The ranges are captured at the same time, so the frames of all but the
+	// first one wait in a spool until the sink can take them in order. When
+	// following the Omega.js commands, every frame waits until the start of
+	// the recording is known.
+	var spooled *spool
+	if workers > 1 || omega != nil {
+		spooled = newSpool(frames, workers * REORDER_FRAMES_PER_WORKER, framesToRecord, omega != nil)
+		frames = spooled
+	}
+	if omega != nil {
+		omega.scheduler, omega.spool = scheduler, spooled
+	}
+
+	// Instantiate the progress bar.
+	bar := pb.StartNew(framesToRecord)
+
+	// Run a goroutine per worker.
+	var wg sync.WaitGroup
+	for _, w := range pool {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			if err := w.run(scheduler, frames, bar); err != nil {
+				// Report the error and release the other workers
+				scheduler.fail(err)
+				abort(frames, err)
 			}
-			// Update the bar
-			bar.Increment()
-			// Notify that a goroutine is available
-			routineReady <- ctx
-		}(ctx, previousFrames[ctx], f)
-		// Update the previousFrames map
-		previousFrames[ctx] = f
+		}(w)
 	}
 
 	// Wait for all running goroutines to finish
-	for availableWorkers < params.Workers {
-		<- routineReady
-		availableWorkers += 1
-	}
+	wg.Wait()
 
 	// Finish the bar
 	bar.Finish()
 
-	return nil
-}
\ No newline at end of file
+	for _, w := range pool {
+		stats.add(w.stats)
+	}
+	stats.Elapsed = time.Since(start)
+
+	if spooled != nil {
+		if err := spooled.Close(); err != nil {
+			scheduler.fail(err)
+		}
+	}
+	// Only the frames between the Omega.js commands were written
+	if omega != nil {
+		stats.Frames = spooled.written()
+	}
+	return stats, scheduler.failed()
+}
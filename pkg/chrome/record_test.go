@@ -2,7 +2,9 @@ package chrome
 
 import (
 	"context"
+	"io/ioutil"
 	"math"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -34,7 +36,7 @@ func (suite *RecordSuite) TestRecord() {
 	params := RecordParams{
 		Duration: 1000,
 		URL     : "https://example.com",
-		Writer  : &suite.writer,
+		Sink    : NewDirectorySink("/tmp", &suite.writer),
 		Workers : 8,
 		Width : 1920,
 		Height: 1080,
@@ -42,9 +44,9 @@ func (suite *RecordSuite) TestRecord() {
 
 	// Default browser behavior
 	browser.Chrome.(*mbrowser.BrowserHandler).On("NewContext", parent).Return(context.WithCancel(context.Background()))
-	browser.Chrome.(*mbrowser.BrowserHandler).On("Navigate", parent, params.URL, mock.AnythingOfType("int64"), mock.AnythingOfType("int64")).Return(nil)
+	browser.Chrome.(*mbrowser.BrowserHandler).On("Navigate", parent, params.URL, browser.Viewport{Width: params.Width, Height: params.Height}).Return(nil)
 	browser.Chrome.(*mbrowser.BrowserHandler).On("Evaluate", parent, mock.AnythingOfType("string")).Return(nil, nil)
-	browser.Chrome.(*mbrowser.BrowserHandler).On("Screenshot", parent).Return(nil, nil)
+	browser.Chrome.(*mbrowser.BrowserHandler).On("Screenshot", parent, mock.Anything).Return(nil, nil)
 	suite.writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
 	// Run the record function
 	record(parent, params)
@@ -53,7 +55,7 @@ func (suite *RecordSuite) TestRecord() {
 	browser.Chrome.(*mbrowser.BrowserHandler).AssertNumberOfCalls(suite.T(), "NewContext", params.Workers)
 
 	// Calculate the amount of frames ro record.
-	framesToRecord := math.Ceil(params.Duration * FPS / 1000)
+	framesToRecord := math.Ceil(params.Duration * DEFAULT_FPS / 1000)
 
 	// Check that the screenshot function was called for each frame
 	browser.Chrome.(*mbrowser.BrowserHandler).AssertNumberOfCalls(suite.T(), "Screenshot", int(framesToRecord))
@@ -67,6 +69,231 @@ func (suite *RecordSuite) TestRecord() {
 	browser.Chrome.(*mbrowser.BrowserHandler).AssertCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(983.333)`)
 }
 
+func (suite *RecordSuite) TestRecordFPS() {
+	previous := browser.Chrome
+	defer func() { browser.Chrome = previous }()
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, nil)
+	writer := &mutils.Writer{}
+	writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+	params := RecordParams{
+		Duration: 1000,
+		FPS     : 29.97,
+		Sink    : NewDirectorySink("/tmp", writer),
+		Workers : 1,
+	}
+	stats, err := record(context.Background(), params)
+	suite.NoError(err)
+
+	// The frame count and times should derive from the 30000/1001 frame rate
+	// passed to ffmpeg
+	suite.Equal(30, stats.Frames)
+	handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(33.367)`)
+	handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(967.633)`)
+	suite.Equal(29, params.Frame(29 * 1001 / 30.0))
+}
+
+func (suite *RecordSuite) TestRange() {
+	previous := browser.Chrome
+	defer func() { browser.Chrome = previous }()
+	setup := func() (*mbrowser.BrowserHandler, *mutils.Writer) {
+		handler := mockBrowser(&mbrowser.BrowserHandler{}, []byte("frame"))
+		writer := &mutils.Writer{}
+		writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+		return handler, writer
+	}
+
+	suite.Run("should record the frames of the range", func() {
+		handler, writer := setup()
+		params := RecordParams{
+			Duration: 1000,
+			StartFrame: 30,
+			EndFrame: 36,
+			Protocol: true,
+			Sink    : NewDirectorySink("/tmp", writer),
+			Workers : 1,
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(6, stats.Frames)
+		suite.Equal(1, stats.Seeks)
+		handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(500.000)`)
+		handler.AssertNotCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(483.333)`)
+		// Frames should be numbered from the start of the range
+		writer.AssertCalled(suite.T(), "WriteFile", "/tmp/000000.png", mock.Anything, mock.Anything)
+		writer.AssertCalled(suite.T(), "WriteFile", "/tmp/000005.png", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("should fail on empty ranges", func() {
+		_, writer := setup()
+		params := RecordParams{Duration: 1000, StartFrame: 60, Sink: NewDirectorySink("/tmp", writer)}
+		_, err := record(context.Background(), params)
+		suite.Error(err)
+	})
+
+	suite.Run("should capture a still with a single seek", func() {
+		handler, writer := setup()
+		at, err := ParseTime("2.5s")
+		suite.NoError(err)
+		params := still(RecordParams{Duration: 1000, FPS: 30}, at, "thumbnail.png", writer)
+		_, err = record(context.Background(), params)
+		suite.NoError(err)
+		suite.NoError(params.Sink.Close())
+		handler.AssertNumberOfCalls(suite.T(), "Evaluate", 1)
+		handler.AssertCalled(suite.T(), "Evaluate", mock.Anything, `timeweb.goTo(2500.000)`)
+		writer.AssertCalled(suite.T(), "WriteFile", "thumbnail.png", []byte("frame"), mock.Anything)
+	})
+}
+
+func (suite *RecordSuite) TestScale() {
+	suite.Run("should default to the browser scale", func() {
+		scale, err := RecordParams{Width: 1920, Height: 1080}.scale()
+		suite.NoError(err)
+		suite.Equal(0.0, scale)
+	})
+
+	suite.Run("should derive the scale from the output size", func() {
+		params := RecordParams{Width: 1920, Height: 1080, OutputWidth: 3840}
+		scale, err := params.scale()
+		suite.NoError(err)
+		suite.Equal(2.0, scale)
+		suite.Equal(browser.Viewport{Width: 1920, Height: 1080, DeviceScaleFactor: 2}, params.viewport())
+	})
+
+	suite.Run("should fail if the output size changes the aspect ratio", func() {
+		_, err := RecordParams{Width: 1920, Height: 1080, OutputWidth: 3840, OutputHeight: 1080}.scale()
+		suite.Error(err)
+		_, err = RecordParams{Width: 1920, Height: 1080, DeviceScaleFactor: 2, OutputWidth: 1920}.scale()
+		suite.Error(err)
+	})
+}
+
+func (suite *RecordSuite) TestFormat() {
+	suite.Run("should capture with the format and quality", func() {
+		previous := browser.Chrome
+		defer func() { browser.Chrome = previous }()
+		handler := mockBrowser(&mbrowser.BrowserHandler{}, []byte("frame"))
+		writer := &mutils.Writer{}
+		writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+		sink := NewDirectorySink("/tmp", writer)
+		sink.Extension = FormatExtension("jpeg")
+		params := RecordParams{Duration: 100, Format: "jpeg", Quality: 70, Sink: sink}
+		_, err := record(context.Background(), params)
+		suite.NoError(err)
+		handler.AssertCalled(suite.T(), "Screenshot", mock.Anything, browser.ScreenshotOptions{Format: "jpeg", Quality: 70})
+		writer.AssertCalled(suite.T(), "WriteFile", "/tmp/000005.jpg", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("should reject formats that can't be used", func() {
+		suite.Error(RecordParams{Format: "gif"}.checkFormat())
+		suite.Error(RecordParams{Format: "jpeg", Transparent: true}.checkFormat())
+		suite.Error(RecordParams{Format: "webp", Supersample: 2}.checkFormat())
+		suite.Error(RecordParams{Format: "jpeg", Quality: 101}.checkFormat())
+		suite.NoError(RecordParams{Format: "webp", Quality: 90, Transparent: true}.checkFormat())
+	})
+
+	suite.Run("should send processed frames as PNG images", func() {
+		suite.Equal("jpeg", RecordParams{Format: "jpeg"}.frameFormat())
+		suite.Equal("png", RecordParams{Format: "jpeg", Supersample: 2}.frameFormat())
+	})
+
+	suite.Run("should record drafts at half the resolution", func() {
+		params := RecordParams{Width: 1920, Height: 1080, OutputWidth: 3840, Draft: true}.draft()
+		suite.Equal("jpeg", params.Format)
+		suite.Equal(DRAFT_QUALITY, params.Quality)
+		suite.Equal(DRAFT_PRESET, params.Preset)
+		suite.Equal(browser.Viewport{Width: 1920, Height: 1080, DeviceScaleFactor: 1}, params.viewport())
+	})
+}
+
+func (suite *RecordSuite) TestParseTime() {
+	for value, expected := range map[string]float64{"1500": 1500, "2.5s": 2500, "1m": 60000, "250ms": 250} {
+		ms, err := ParseTime(value)
+		suite.NoError(err)
+		suite.Equal(expected, ms)
+	}
+	_, err := ParseTime("soon")
+	suite.Error(err)
+}
+
+func (suite *RecordSuite) TestFrameRange() {
+	params := RecordParams{FPS: 60}
+
+	// Frames and times include the last frame alike
+	start, end, err := params.FrameRange(300, 420, "", "")
+	suite.NoError(err)
+	suite.Equal([]int{300, 421}, []int{start, end})
+	start, end, err = params.FrameRange(0, -1, "5s", "7s")
+	suite.NoError(err)
+	suite.Equal([]int{300, 421}, []int{start, end})
+
+	// Without an end, the recording goes up to the end of the duration
+	start, end, err = params.FrameRange(10, -1, "", "")
+	suite.NoError(err)
+	suite.Equal([]int{10, 0}, []int{start, end})
+
+	_, _, err = params.FrameRange(0, -1, "", "later")
+	suite.Error(err)
+}
+
+func (suite *RecordSuite) TestPage() {
+	suite.Run("should default to the handler", func() {
+		url, root, err := RecordParams{}.page(38080)
+		suite.NoError(err)
+		suite.Equal("http://localhost:38080/handler", url)
+		suite.Equal("", root)
+	})
+
+	suite.Run("should record the provided URL", func() {
+		url, root, err := RecordParams{URL: "https://example.com"}.page(38080)
+		suite.NoError(err)
+		suite.Equal("https://example.com", url)
+		suite.Equal("", root)
+	})
+
+	suite.Run("should serve the directory of a file", func() {
+		dir := suite.T().TempDir()
+		suite.NoError(ioutil.WriteFile(filepath.Join(dir, "intro page.html"), []byte("<html></html>"), 0644))
+		url, root, err := RecordParams{File: filepath.Join(dir, "intro page.html")}.page(38080)
+		suite.NoError(err)
+		suite.Equal("http://localhost:38080/project/intro%20page.html", url)
+		suite.Equal(dir, root)
+	})
+
+	suite.Run("should serve a directory", func() {
+		dir := suite.T().TempDir()
+		url, root, err := RecordParams{Dir: dir}.page(38080)
+		suite.NoError(err)
+		suite.Equal("http://localhost:38080/project/", url)
+		suite.Equal(dir, root)
+	})
+
+	suite.Run("should record the dev page of an entry point", func() {
+		url, root, err := RecordParams{EntryPoint: "./index.js"}.page(38080)
+		suite.NoError(err)
+		suite.Equal("http://localhost:38080/dev?timeweb=true", url)
+		suite.Equal("", root)
+
+		// The virtual time doesn't need timeweb.js
+		url, _, err = RecordParams{EntryPoint: "./index.js", Time: VIRTUAL_TIME}.page(38080)
+		suite.NoError(err)
+		suite.Equal("http://localhost:38080/dev", url)
+	})
+
+	suite.Run("should fail on missing files and directories", func() {
+		_, _, err := RecordParams{File: "./missing.html"}.page(38080)
+		suite.Error(err)
+		_, _, err = RecordParams{Dir: "./missing"}.page(38080)
+		suite.Error(err)
+	})
+
+	suite.Run("should accept a single source", func() {
+		_, _, err := RecordParams{URL: "https://example.com", Dir: "."}.page(38080)
+		suite.Error(err)
+	})
+}
+
 // Run the test suite
 func TestRecordSuite(t *testing.T) {
 	suite.Run(t, new(RecordSuite))
@@ -0,0 +1,172 @@
+package chrome
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// span is a contiguous range of frames, from next up to, but not including,
+// end.
+type span struct {
+	next int
+	end int
+}
+
+// scheduler splits the frames of a recording into one contiguous range per
+// worker, so each browser context only has to seek once to the start of its
+// range and then advance one frame at a time. Workers that finish their range
+// steal the second half of the largest remaining one. Once a worker fails,
+// the scheduler stops handing out frames.
+type scheduler struct {
+	// start is the first frame of the recording.
+	start int
+	mu sync.Mutex
+	spans []*span
+	// err holds the first error reported by a worker.
+	err error
+	// done is closed when a worker fails.
+	done chan struct{}
+}
+
+// newScheduler splits the frames between start and end into a range per
+// worker.
+func newScheduler(start, end, workers int) *scheduler {
+	s := &scheduler{start: start, spans: make([]*span, workers), done: make(chan struct{})}
+	frames := end - start
+	for w := 0; w < workers; w++ {
+		s.spans[w] = &span{
+			next: start + frames * w / workers,
+			end: start + frames * (w + 1) / workers,
+		}
+	}
+	return s
+}
+
+// take returns the next frame of the worker range, or false if the range is
+// done.
+func (s *scheduler) take(worker int) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := s.spans[worker]
+	if s.err != nil || span.next >= span.end {
+		return 0, false
+	}
+	frame := span.next
+	span.next++
+	return frame, true
+}
+
+// steal moves the second half of the largest remaining range to the worker.
+// It returns false if there is nothing left to steal.
+func (s *scheduler) steal(worker int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return false
+	}
+
+	// Find the range with the most frames left
+	var victim *span
+	for w, span := range s.spans {
+		if w != worker && (victim == nil || span.end - span.next > victim.end - victim.next) {
+			victim = span
+		}
+	}
+	// The victim keeps at least the frame it is about to take.
+	if victim == nil || victim.end - victim.next < 2 {
+		return false
+	}
+	middle := victim.next + (victim.end - victim.next + 1) / 2
+	s.spans[worker] = &span{next: middle, end: victim.end}
+	victim.end = middle
+	return true
+}
+
+// cut stops handing out the frames from end on.
+func (s *scheduler) cut(end int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, span := range s.spans {
+		if span.end > end {
+			span.end = end
+		}
+	}
+}
+
+// fail stops handing out frames and closes the done channel, so the other
+// workers cancel their browser contexts. Only the first error is kept.
+func (s *scheduler) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+		close(s.done)
+	}
+}
+
+// failed returns the first error reported by a worker, if any.
+func (s *scheduler) failed() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// RecordStats holds statistics about a recording.
+type RecordStats struct {
+	// Frames counts the recorded frames.
+	Frames int
+	// Evaluations counts the calls to `timeweb.goTo`.
+	Evaluations int
+	// Seeks counts the evaluations that jumped to a frame that doesn't follow
+	// the last one of the browser context.
+	Seeks int
+	// Steals counts the ranges stolen by idle workers.
+	Steals int
+	// Time spent on each step, added up between workers.
+	EvaluateTime time.Duration
+	ScreenshotTime time.Duration
+	WriteTime time.Duration
+	// Elapsed is the wall time of the recording.
+	Elapsed time.Duration
+}
+
+// add accumulates the stats of a worker.
+func (s *RecordStats) add(other RecordStats) {
+	s.Frames += other.Frames
+	s.Evaluations += other.Evaluations
+	s.Seeks += other.Seeks
+	s.Steals += other.Steals
+	s.EvaluateTime += other.EvaluateTime
+	s.ScreenshotTime += other.ScreenshotTime
+	s.WriteTime += other.WriteTime
+}
+
+// perFrame returns the average of a duration per frame.
+func (s RecordStats) perFrame(d time.Duration) time.Duration {
+	if s.Frames == 0 {
+		return 0
+	}
+	return d / time.Duration(s.Frames)
+}
+
+// String summarizes the stats per frame.
+func (s RecordStats) String() string {
+	evaluations := 0.0
+	if s.Frames > 0 {
+		evaluations = float64(s.Evaluations) / float64(s.Frames)
+	}
+	return fmt.Sprintf(
+		"%d frames in %s: %.2f evaluations per frame (%d seeks, %d steals), per frame evaluate %s, screenshot %s, write %s",
+		s.Frames,
+		s.Elapsed.Round(time.Millisecond),
+		evaluations,
+		s.Seeks,
+		s.Steals,
+		s.perFrame(s.EvaluateTime).Round(time.Microsecond),
+		s.perFrame(s.ScreenshotTime).Round(time.Microsecond),
+		s.perFrame(s.WriteTime).Round(time.Microsecond),
+	)
+}
@@ -0,0 +1,155 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	mutils "gux.codes/omega/mocks/utils"
+	"gux.codes/omega/pkg/browser"
+)
+
+type SchedulerSuite struct {
+	suite.Suite
+}
+
+func (suite *SchedulerSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+// frames takes every frame left on the range of a worker.
+func (suite *SchedulerSuite) frames(s *scheduler, worker int) []int {
+	frames := make([]int, 0)
+	for {
+		f, ok := s.take(worker)
+		if !ok {
+			return frames
+		}
+		frames = append(frames, f)
+	}
+}
+
+func (suite *SchedulerSuite) TestScheduler() {
+	suite.Run("should split the frames in contiguous ranges", func() {
+		s := newScheduler(0, 10, 3)
+		suite.Equal([]int{0, 1, 2}, suite.frames(s, 0))
+		suite.Equal([]int{3, 4, 5}, suite.frames(s, 1))
+		suite.Equal([]int{6, 7, 8, 9}, suite.frames(s, 2))
+	})
+
+	suite.Run("should steal the second half of the largest range", func() {
+		s := newScheduler(0, 10, 2)
+		suite.Equal([]int{0, 1, 2, 3, 4}, suite.frames(s, 0))
+		f, _ := s.take(1)
+		suite.Equal(5, f)
+		suite.True(s.steal(0))
+		suite.Equal([]int{8, 9}, suite.frames(s, 0))
+		suite.Equal([]int{6, 7}, suite.frames(s, 1))
+		suite.False(s.steal(0))
+	})
+
+	suite.Run("should stop handing out frames once a worker fails", func() {
+		s := newScheduler(10, 20, 2)
+		f, _ := s.take(0)
+		suite.Equal(10, f)
+		s.fail(errors.New("failed"))
+		suite.Empty(suite.frames(s, 0))
+		suite.Empty(suite.frames(s, 1))
+		suite.False(s.steal(0))
+	})
+}
+
+func (suite *SchedulerSuite) TestRecordStats() {
+	handler := mockBrowser(&mbrowser.BrowserHandler{}, nil)
+	writer := &mutils.Writer{}
+	writer.On("WriteFile", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+	params := RecordParams{
+		Duration: 1000,
+		Sink    : NewDirectorySink("/tmp", writer),
+		Workers : 4,
+		Width : 1920,
+		Height: 1080,
+	}
+	stats, err := record(context.Background(), params)
+	suite.NoError(err)
+
+	// Each worker should seek once to the start of its range, or of a stolen
+	// range, and then advance one frame at a time
+	suite.Equal(60, stats.Frames)
+	suite.Equal(59, stats.Evaluations)
+	suite.Equal(3 + stats.Steals, stats.Seeks)
+	handler.AssertNumberOfCalls(suite.T(), "Evaluate", 59)
+}
+
+func (suite *SchedulerSuite) TestParallelOrderedSink() {
+	// Each screenshot waits for the screenshots of the same rank on the other
+	// browser contexts, which never happens if a worker waits for the sink
+	workers := 8
+	var mu sync.Mutex
+	var times pageTimes
+	ranks := make(map[context.Context]int)
+	barriers := make(map[int]chan struct{})
+	arrived := make(map[int]int)
+	var blocked int32
+	handler := &mbrowser.BrowserHandler{}
+	handler.On("Evaluate", mock.Anything, mock.AnythingOfType("string")).Return(times.evaluate, nil)
+	handler.On("Screenshot", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, options browser.ScreenshotOptions) []byte {
+			mu.Lock()
+			ranks[ctx]++
+			rank := ranks[ctx]
+			if barriers[rank] == nil {
+				barriers[rank] = make(chan struct{})
+			}
+			barrier := barriers[rank]
+			if arrived[rank]++; arrived[rank] == workers {
+				close(barrier)
+			}
+			mu.Unlock()
+			if atomic.LoadInt32(&blocked) == 0 {
+				select {
+				case <- barrier:
+				case <- time.After(5 * time.Second):
+					atomic.StoreInt32(&blocked, 1)
+				}
+			}
+			return []byte(fmt.Sprintf("%-24s\n", times.time(ctx)))
+		},
+		nil,
+	)
+	mockBrowser(handler, nil)
+
+	var output bytes.Buffer
+	params := RecordParams{
+		Duration: 4000,
+		Sink    : NewOrderedWriter(&output, workers * REORDER_FRAMES_PER_WORKER),
+		Workers : workers,
+	}
+	stats, err := record(context.Background(), params)
+	suite.NoError(err)
+	suite.Equal(int32(0), atomic.LoadInt32(&blocked), "the workers waited for each other")
+
+	// Every frame should have been written in order, with a seek per range
+	suite.Equal(240, stats.Frames)
+	suite.Equal(workers - 1 + stats.Steals, stats.Seeks)
+	frames := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	suite.Len(frames, 240)
+	for i, frame := range frames {
+		suite.Equal(goTo(i), strings.TrimSpace(frame))
+	}
+}
+
+// Run the test suite
+func TestSchedulerSuite(t *testing.T) {
+	suite.Run(t, new(SchedulerSuite))
+}
@@ -13,17 +13,50 @@ import (
 type WebServerOptions struct {
 	// Port from which to run the server
 	Port int
+	// Root is a directory served as static files under /project, if set.
+	Root string
+	// Params are passed to the templates, and exposed on the page as
+	// `Omega.params`.
+	Params gin.H
+	// Variants hold the params of each variant of a batch, selected with the
+	// `variant` query param.
+	Variants map[string]gin.H
+}
+
+// params returns the template params of the requested variant, or the
+// default ones.
+func (options WebServerOptions) params(c *gin.Context) gin.H {
+	if params, ok := options.Variants[c.Query("variant")]; ok {
+		return params
+	}
+	return options.Params
 }
 
 // NewWebServerOptions creates a default WebServerOptions struct.
 func NewWebServerOptions() WebServerOptions {
 	return WebServerOptions{
 		Port: 38080,
+		Params: gin.H{
+			"width": 1920,
+			"height": 1080,
+			"fps": DEFAULT_FPS,
+			"duration": 1000,
+		},
 	}
 }
 
 var cssRe = regexp.MustCompile(`\.css$`)
 
+// templateData returns the data of a template: every param as is, like
+// `{{ .width }}`, and the whole params object as `{{ .params }}`.
+func templateData(params gin.H) gin.H {
+	data := gin.H{"params": params}
+	for key, value := range params {
+		data[key] = value
+	}
+	return data
+}
+
 // start the server from which the handler function is served.
 func Serve(options WebServerOptions) {
 	defer func() {
@@ -35,6 +68,12 @@ func Serve(options WebServerOptions) {
 	gin.ForceConsoleColor()
 	// Set the "release" mode
 	gin.SetMode(gin.ReleaseMode)
+	// Run the server
+	newRouter(options).Run(fmt.Sprintf(":%d", options.Port))
+}
+
+// newRouter creates the router of the web server.
+func newRouter(options WebServerOptions) *gin.Engine {
 	// Create the default router
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -50,12 +89,19 @@ func Serve(options WebServerOptions) {
 		assets = "./assets"
 	}
 	router.Static("/assets", assets)
+	// Serve the recorded project
+	if options.Root != "" {
+		router.Static("/project", options.Root)
+	}
 	// Create the routes
 	router.GET("/handler", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "three.html.tmpl", nil)
+		c.HTML(http.StatusOK, "three.html.tmpl", templateData(options.params(c)))
 	})
 	router.GET("/dev", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "dev.html.tmpl", nil)
+		data := templateData(options.params(c))
+		// Recordings need timeweb.js to control the time of the page
+		data["timeweb"] = c.Query("timeweb") == "true"
+		c.HTML(http.StatusOK, "dev.html.tmpl", data)
 	})
 	router.GET("/dev/:asset", func(c *gin.Context) {
 		asset   := c.Param("asset")
@@ -65,7 +111,6 @@ func Serve(options WebServerOptions) {
 		}
 		c.String(http.StatusOK, content)
 	})
-	// Run the server
-	router.Run(fmt.Sprintf(":%d", options.Port))
+	return router
 }
 
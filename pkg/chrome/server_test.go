@@ -0,0 +1,91 @@
+package chrome
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServerSuite struct {
+	suite.Suite
+}
+
+func (suite *ServerSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("OMEGA_SERVER_TEMPLATES", "../../templates/*")
+	os.Setenv("OMEGA_SERVER_ASSETS", "../../assets")
+}
+
+func (suite *ServerSuite) TearDownSuite() {
+	os.Unsetenv("OMEGA_SERVER_TEMPLATES")
+	os.Unsetenv("OMEGA_SERVER_ASSETS")
+}
+
+// get returns the body of a request to the router.
+func (suite *ServerSuite) get(options WebServerOptions, path string) string {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	newRouter(options).ServeHTTP(recorder, request)
+	suite.Equal(http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func (suite *ServerSuite) TestParams() {
+	params := RecordParams{
+		Duration: 2000,
+		FPS     : 25,
+		Width   : 1280,
+		Height  : 720,
+		Data    : map[string]interface{}{"customer": "ACME"},
+	}
+	options := NewWebServerOptions()
+	options.Params = params.templateParams()
+
+	suite.Run("should expose the params on the handler page", func() {
+		body := suite.get(options, "/handler")
+		suite.Contains(body, `window.OMEGA_PARAMS = {"data":{"customer":"ACME"},"duration":2000,"fps":25,"height":720,"width":1280};`)
+	})
+
+	suite.Run("should expose the params on the dev page", func() {
+		body := suite.get(options, "/dev?timeweb=true")
+		suite.Contains(body, `"customer":"ACME"`)
+		suite.Contains(body, `/assets/timeweb.js`)
+		suite.NotContains(suite.get(options, "/dev"), `/assets/timeweb.js`)
+	})
+}
+
+func (suite *ServerSuite) TestLoadData() {
+	dir := suite.T().TempDir()
+
+	suite.Run("should load JSON files", func() {
+		path := filepath.Join(dir, "data.json")
+		suite.NoError(ioutil.WriteFile(path, []byte(`{"customer": "ACME", "colors": ["red"]}`), 0644))
+		data, err := LoadData(path)
+		suite.NoError(err)
+		suite.Equal(map[string]interface{}{"customer": "ACME", "colors": []interface{}{"red"}}, data)
+	})
+
+	suite.Run("should load YAML files", func() {
+		path := filepath.Join(dir, "data.yml")
+		suite.NoError(ioutil.WriteFile(path, []byte("customer: ACME\nlanguage: es\n"), 0644))
+		data, err := LoadData(path)
+		suite.NoError(err)
+		suite.Equal(map[string]interface{}{"customer": "ACME", "language": "es"}, data)
+	})
+
+	suite.Run("should fail on missing files", func() {
+		_, err := LoadData(filepath.Join(dir, "missing.yml"))
+		suite.Error(err)
+	})
+}
+
+// Run the test suite
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(ServerSuite))
+}
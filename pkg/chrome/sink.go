@@ -0,0 +1,203 @@
+package chrome
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gux.codes/omega/pkg/utils"
+)
+
+// FrameSink receives the frames of a recording identified by their index.
+// Workers write frames concurrently and out of order.
+type FrameSink interface {
+	// WriteFrame stores the image of a frame.
+	WriteFrame(index int, image []byte) error
+	// Close flushes the sink once every frame was written.
+	Close() error
+	// Err returns the first error of the sink, if any.
+	Err() error
+}
+
+// aborter is implemented by the sinks that hold resources, or writers blocked
+// on them, that must be released when the recording fails. Aborted sinks
+// don't have to be closed.
+type aborter interface {
+	Abort(err error)
+}
+
+// abort aborts the sink if it can be aborted.
+func abort(sink FrameSink, err error) {
+	if a, ok := sink.(aborter); ok {
+		a.Abort(err)
+	}
+}
+
+// sinkError keeps the first error of a sink.
+type sinkError struct {
+	mu sync.Mutex
+	err error
+}
+
+// set stores err if no error was stored before, and returns it.
+func (s *sinkError) set(err error) error {
+	if err == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	return err
+}
+
+// Err returns the first stored error.
+func (s *sinkError) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// DirectorySink writes each frame to a numbered file of a directory.
+type DirectorySink struct {
+	sinkError
+	// Dir is the directory of the frames.
+	Dir string
+	// Extension of the frame files. Defaults to png.
+	Extension string
+	writer utils.Writer
+}
+
+// NewDirectorySink creates a DirectorySink that writes the frames to dir
+// through a utils.Writer.
+func NewDirectorySink(dir string, writer utils.Writer) *DirectorySink {
+	return &DirectorySink{Dir: dir, writer: writer}
+}
+
+// WriteFrame writes the frame to {{ dir }}/{{ index }}.{{ extension }}
+func (s *DirectorySink) WriteFrame(index int, image []byte) error {
+	path := filepath.Join(s.Dir, frameName(index, s.Extension))
+	return s.set(s.writer.WriteFile(path, image, 0644))
+}
+
+// Close returns the first error of the sink. The files are already written.
+func (s *DirectorySink) Close() error {
+	return s.Err()
+}
+
+// frameName returns the file name of a frame.
+func frameName(index int, extension string) string {
+	if extension == "" {
+		extension = "png"
+	}
+	return fmt.Sprintf("%06d.%s", index, extension)
+}
+
+// ZipSink stores each frame as a numbered entry of a zip archive.
+type ZipSink struct {
+	sinkError
+	// Path of the archive.
+	Path string
+	// Extension of the frame entries. Defaults to png.
+	Extension string
+	file *os.File
+	archive *zip.Writer
+}
+
+// NewZipSink creates the zip archive at path.
+func NewZipSink(path string) (*ZipSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipSink{Path: path, file: file, archive: zip.NewWriter(file)}, nil
+}
+
+// WriteFrame adds the frame to the archive as {{ index }}.{{ extension }}. The
+// frames are stored without compression, images are already compressed.
+func (s *ZipSink) WriteFrame(index int, image []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	entry, err := s.archive.CreateHeader(&zip.FileHeader{
+		Name: frameName(index, s.Extension),
+		Method: zip.Store,
+	})
+	if err == nil {
+		_, err = entry.Write(image)
+	}
+	if err != nil {
+		s.err = err
+	}
+	return err
+}
+
+// Close writes the directory of the archive and closes its file.
+func (s *ZipSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.archive.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Abort closes and removes the incomplete archive.
+func (s *ZipSink) Abort(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	_ = s.file.Close()
+	_ = os.Remove(s.Path)
+}
+
+// TeeSink writes every frame to several sinks.
+type TeeSink struct {
+	sinkError
+	sinks []FrameSink
+}
+
+// NewTeeSink creates a TeeSink that writes to every provided sink.
+func NewTeeSink(sinks ...FrameSink) *TeeSink {
+	return &TeeSink{sinks: sinks}
+}
+
+// WriteFrame writes the frame to each sink, stopping on the first error.
+func (s *TeeSink) WriteFrame(index int, image []byte) error {
+	if err := s.Err(); err != nil {
+		return err
+	}
+	for _, sink := range s.sinks {
+		if err := sink.WriteFrame(index, image); err != nil {
+			return s.set(err)
+		}
+	}
+	return nil
+}
+
+// Close closes every sink and returns the first error.
+func (s *TeeSink) Close() error {
+	for _, sink := range s.sinks {
+		_ = s.set(sink.Close())
+	}
+	return s.Err()
+}
+
+// Abort aborts every sink that can be aborted.
+func (s *TeeSink) Abort(err error) {
+	_ = s.set(err)
+	for _, sink := range s.sinks {
+		abort(sink, err)
+	}
+}
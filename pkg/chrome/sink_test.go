@@ -0,0 +1,117 @@
+package chrome
+
+import (
+	"archive/zip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mchrome "gux.codes/omega/mocks/chrome"
+	mutils "gux.codes/omega/mocks/utils"
+)
+
+type SinkSuite struct {
+	suite.Suite
+}
+
+func (suite *SinkSuite) TestDirectorySink() {
+	writer := &mutils.Writer{}
+	failed := errors.New("disk full")
+	writer.On("WriteFile", "frames/000003.png", []byte("3"), os.FileMode(0644)).Return(nil)
+	writer.On("WriteFile", "frames/000004.png", []byte("4"), os.FileMode(0644)).Return(failed)
+
+	sink := NewDirectorySink("frames", writer)
+	suite.NoError(sink.WriteFrame(3, []byte("3")))
+	suite.Equal(failed, sink.WriteFrame(4, []byte("4")))
+	suite.Equal(failed, sink.Err())
+	suite.Equal(failed, sink.Close())
+}
+
+func (suite *SinkSuite) TestZipSink() {
+	dir := suite.T().TempDir()
+
+	suite.Run("should store every frame", func() {
+		path := filepath.Join(dir, "frames.zip")
+		sink, err := NewZipSink(path)
+		suite.NoError(err)
+		suite.NoError(sink.WriteFrame(1, []byte("b")))
+		suite.NoError(sink.WriteFrame(0, []byte("a")))
+		suite.NoError(sink.Close())
+
+		archive, err := zip.OpenReader(path)
+		suite.NoError(err)
+		defer archive.Close()
+		suite.Len(archive.File, 2)
+		suite.Equal("000001.png", archive.File[0].Name)
+		entry, err := archive.File[1].Open()
+		suite.NoError(err)
+		content, err := ioutil.ReadAll(entry)
+		suite.NoError(err)
+		suite.Equal("a", string(content))
+	})
+
+	suite.Run("should remove the archive when aborted", func() {
+		path := filepath.Join(dir, "aborted.zip")
+		sink, err := NewZipSink(path)
+		suite.NoError(err)
+		suite.NoError(sink.WriteFrame(0, []byte("a")))
+		sink.Abort(errors.New("canceled"))
+		_, err = os.Stat(path)
+		suite.True(os.IsNotExist(err))
+	})
+}
+
+func (suite *SinkSuite) TestTeeSink() {
+	first, second := &mchrome.FrameSink{}, &mchrome.FrameSink{}
+	failed := errors.New("encoder failed")
+	first.On("WriteFrame", mock.Anything, mock.Anything).Return(nil)
+	second.On("WriteFrame", 0, mock.Anything).Return(nil)
+	second.On("WriteFrame", 1, mock.Anything).Return(failed)
+	first.On("Close").Return(nil)
+	second.On("Close").Return(failed)
+
+	sink := NewTeeSink(first, second)
+	suite.NoError(sink.WriteFrame(0, []byte("a")))
+	suite.Equal(failed, sink.WriteFrame(1, []byte("b")))
+	// The sink should stop writing after an error
+	suite.Equal(failed, sink.WriteFrame(2, []byte("c")))
+	first.AssertNumberOfCalls(suite.T(), "WriteFrame", 2)
+
+	// Every sink should be closed
+	suite.Equal(failed, sink.Close())
+	first.AssertCalled(suite.T(), "Close")
+	second.AssertCalled(suite.T(), "Close")
+}
+
+func (suite *SinkSuite) TestSink() {
+	suite.Run("should fail without outputs", func() {
+		_, _, err := RecordParams{NoVideo: true}.sink()
+		suite.Error(err)
+	})
+
+	suite.Run("should tee the frames directory and the zip archive", func() {
+		dir := suite.T().TempDir()
+		params := RecordParams{
+			NoVideo: true,
+			FramesDir: filepath.Join(dir, "frames"),
+			Zip: filepath.Join(dir, "archive", "frames.zip"),
+		}
+		sink, output, err := params.sink()
+		suite.NoError(err)
+		suite.IsType(&TeeSink{}, sink)
+		suite.Equal(params.FramesDir + ", " + params.Zip, output)
+		suite.NoError(sink.WriteFrame(0, []byte("a")))
+		suite.NoError(sink.Close())
+		suite.FileExists(filepath.Join(params.FramesDir, "000000.png"))
+		suite.FileExists(params.Zip)
+	})
+}
+
+// Run the test suite
+func TestSinkSuite(t *testing.T) {
+	suite.Run(t, new(SinkSuite))
+}
@@ -0,0 +1,257 @@
+package chrome
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// spooledFrame is the position of a frame in the spool file.
+type spooledFrame struct {
+	offset int64
+	length int
+}
+
+// spool is a FrameSink that passes the frames to another sink strictly by
+// frame index. Each worker captures its own range of frames, so most frames
+// arrive long before their turn: up to capacity of them are held in memory,
+// and the others are spooled to a temporary file until the frames before them
+// are written. The sinks after it, like an OrderedWriter, receive the frames
+// in order and never make the workers wait for each other.
+//
+// A held spool doesn't pass any frame until begin tells which one the
+// recording starts from, and frames from the end set by cut are dropped, so
+// the page can bound the recording while it is captured.
+type spool struct {
+	sink FrameSink
+	// capacity is the number of frames ahead of the next one held in memory.
+	capacity int
+	mu sync.Mutex
+	// next is the index of the next frame to pass to the sink.
+	next int
+	// held is true until begin is called on a held spool.
+	held bool
+	// offset is subtracted from the index of the frames passed to the sink.
+	offset int
+	// end is the index of the first frame that is dropped.
+	end int
+	// passed counts the frames passed to the sink.
+	passed int
+	// draining is true while a writer passes frames to the sink.
+	draining bool
+	// pending holds the frames held in memory.
+	pending map[int][]byte
+	// file holds the frames of spooled, and is created on first use.
+	file *os.File
+	size int64
+	spooled map[int]spooledFrame
+	err error
+}
+
+// newSpool creates a spool that writes up to end frames to the sink and holds
+// up to capacity frames in memory. A held spool waits for begin.
+func newSpool(sink FrameSink, capacity int, end int, held bool) *spool {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &spool{
+		sink: sink,
+		capacity: capacity,
+		held: held,
+		end: end,
+		pending: make(map[int][]byte),
+		spooled: make(map[int]spooledFrame),
+	}
+}
+
+// WriteFrame holds the frame until its turn, and writes every consecutive
+// frame that is ready unless another writer already does.
+func (s *spool) WriteFrame(index int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	// Drop the frames out of the bounds of the recording
+	if index >= s.end || index < s.offset {
+		return nil
+	}
+	_, held := s.pending[index]
+	_, spooled := s.spooled[index]
+	if held || spooled || index < s.next {
+		return fmt.Errorf("frame %d was already written", index)
+	}
+	if index < s.next + s.capacity {
+		s.pending[index] = data
+	} else if err := s.store(index, data); err != nil {
+		s.err = err
+		return err
+	}
+	return s.drain()
+}
+
+// drain writes every consecutive frame that is ready, unless the spool is held
+// or another writer already does. The lock is released while writing.
+func (s *spool) drain() error {
+	if s.held || s.draining {
+		return nil
+	}
+	s.draining = true
+	defer func() { s.draining = false }()
+	for s.next < s.end {
+		frame, ok, err := s.take(s.next)
+		if err != nil {
+			s.err = err
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		index := s.next - s.offset
+		s.next++
+		s.mu.Unlock()
+		err = s.sink.WriteFrame(index, frame)
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		if s.err != nil {
+			return s.err
+		}
+		s.passed++
+	}
+	return nil
+}
+
+// begin starts passing frames to the sink from the frame start, numbered from
+// 0. The frames before it are dropped.
+func (s *spool) begin(start int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil || !s.held {
+		return s.err
+	}
+	s.drop(func(index int) bool { return index < start })
+	s.held, s.next, s.offset = false, start, start
+	return s.drain()
+}
+
+// cut drops the frames from end on.
+func (s *spool) cut(end int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if end < s.end {
+		s.end = end
+		s.drop(func(index int) bool { return index >= end })
+	}
+}
+
+// drop removes the frames that match from the memory and the spool file.
+func (s *spool) drop(match func(index int) bool) {
+	for index := range s.pending {
+		if match(index) {
+			delete(s.pending, index)
+		}
+	}
+	for index := range s.spooled {
+		if match(index) {
+			delete(s.spooled, index)
+		}
+	}
+}
+
+// store appends the frame to the spool file.
+func (s *spool) store(index int, data []byte) error {
+	if s.file == nil {
+		file, err := ioutil.TempFile("", "omega-frames-*")
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+	if _, err := s.file.WriteAt(data, s.size); err != nil {
+		return err
+	}
+	s.spooled[index] = spooledFrame{offset: s.size, length: len(data)}
+	s.size += int64(len(data))
+	return nil
+}
+
+// take removes a frame from the memory or the spool file, and returns false
+// if it didn't arrive yet. The spool file is emptied once every frame it holds
+// was taken.
+func (s *spool) take(index int) ([]byte, bool, error) {
+	if data, ok := s.pending[index]; ok {
+		delete(s.pending, index)
+		return data, true, nil
+	}
+	frame, ok := s.spooled[index]
+	if !ok {
+		return nil, false, nil
+	}
+	data := make([]byte, frame.length)
+	if _, err := s.file.ReadAt(data, frame.offset); err != nil {
+		return nil, false, err
+	}
+	delete(s.spooled, index)
+	if len(s.spooled) == 0 {
+		s.size = 0
+		if err := s.file.Truncate(0); err != nil {
+			return nil, false, err
+		}
+	}
+	return data, true, nil
+}
+
+// remove deletes the spool file.
+func (s *spool) remove() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
+
+// Close fails if frames are still waiting for the ones before them, or for
+// begin, and deletes the spool file. It doesn't close the underlying sink.
+func (s *spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove()
+	if s.err == nil && s.held {
+		s.err = errors.New("the recording never started")
+	}
+	if waiting := len(s.pending) + len(s.spooled); s.err == nil && waiting > 0 {
+		s.err = fmt.Errorf("%d frames are waiting for frame %d", waiting, s.next)
+	}
+	return s.err
+}
+
+// Abort makes every future WriteFrame call fail with err, deletes the spool
+// file and aborts the underlying sink.
+func (s *spool) Abort(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.remove()
+	s.mu.Unlock()
+	abort(s.sink, err)
+}
+
+// written returns the number of frames passed to the sink.
+func (s *spool) written() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.passed
+}
+
+// Err returns the first error of the spool.
+func (s *spool) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,90 @@
+package chrome
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SpoolSuite struct {
+	suite.Suite
+}
+
+func (suite *SpoolSuite) TestWriteFrame() {
+	suite.Run("should spool the frames that don't fit in memory", func() {
+		var output bytes.Buffer
+		s := newSpool(NewOrderedWriter(&output, 1), 2, 10, false)
+		for _, i := range []int{4, 3, 2, 1} {
+			suite.NoError(s.WriteFrame(i, []byte{'0' + byte(i)}))
+		}
+		suite.Len(s.pending, 1)
+		suite.Len(s.spooled, 3)
+		suite.Equal("", output.String())
+		name := s.file.Name()
+
+		// The frames should be passed in order once the first one arrives
+		suite.NoError(s.WriteFrame(0, []byte("0")))
+		suite.Equal("01234", output.String())
+		suite.Empty(s.spooled)
+		suite.Equal(int64(0), s.size)
+		suite.Error(s.WriteFrame(2, []byte("2")))
+
+		suite.NoError(s.Close())
+		_, err := os.Stat(name)
+		suite.True(os.IsNotExist(err))
+	})
+
+	suite.Run("should hold the frames until the recording begins", func() {
+		var output bytes.Buffer
+		s := newSpool(NewOrderedWriter(&output, 1), 2, 10, true)
+		for _, i := range []int{0, 1, 2, 3, 4, 7} {
+			suite.NoError(s.WriteFrame(i, []byte{'0' + byte(i)}))
+		}
+		suite.Equal("", output.String())
+
+		// The frames before the start and after the end should be dropped, and
+		// the others numbered from the start
+		s.cut(6)
+		suite.NoError(s.begin(2))
+		suite.Equal("234", output.String())
+		suite.NoError(s.WriteFrame(1, []byte("1")))
+		suite.NoError(s.WriteFrame(5, []byte("5")))
+		suite.NoError(s.WriteFrame(8, []byte("8")))
+		suite.Equal("2345", output.String())
+		suite.Equal(4, s.written())
+		suite.NoError(s.Close())
+	})
+
+	suite.Run("should fail to close before the recording begins", func() {
+		s := newSpool(NewOrderedWriter(&bytes.Buffer{}, 1), 1, 10, true)
+		suite.EqualError(s.Close(), "the recording never started")
+	})
+
+	suite.Run("should fail to close while frames are waiting", func() {
+		s := newSpool(NewOrderedWriter(&bytes.Buffer{}, 1), 1, 10, false)
+		suite.NoError(s.WriteFrame(1, []byte("1")))
+		suite.NoError(s.WriteFrame(3, []byte("3")))
+		suite.EqualError(s.Close(), "2 frames are waiting for frame 0")
+	})
+
+	suite.Run("should abort the sink and delete the spool file", func() {
+		writer := NewOrderedWriter(&bytes.Buffer{}, 1)
+		s := newSpool(writer, 1, 10, false)
+		suite.NoError(s.WriteFrame(2, []byte("2")))
+		name := s.file.Name()
+		aborted := errors.New("aborted")
+		s.Abort(aborted)
+		suite.Equal(aborted, s.WriteFrame(0, []byte("0")))
+		suite.Equal(aborted, writer.Err())
+		_, err := os.Stat(name)
+		suite.True(os.IsNotExist(err))
+	})
+}
+
+// Run the test suite
+func TestSpoolSuite(t *testing.T) {
+	suite.Run(t, new(SpoolSuite))
+}
@@ -0,0 +1,127 @@
+package chrome
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gux.codes/omega/pkg/utils"
+)
+
+// ParseTime parses a time of the animation, like 2.5s or 1m30s, and returns
+// it in ms. Numbers without a unit are taken as ms.
+func ParseTime(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.ParseFloat(value, 64); err == nil {
+		return ms, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use ms or a duration like 2.5s", value)
+	}
+	return float64(d) / float64(time.Millisecond), nil
+}
+
+// FrameRange converts the first and last frames to record, both included, to
+// a StartFrame and an exclusive EndFrame. from and to set the same frames as
+// times, like 2.5s, and take precedence. A negative last frame, with no to,
+// records up to the end of the Duration and returns an EndFrame of 0.
+func (params RecordParams) FrameRange(first, last int, from, to string) (int, int, error) {
+	if from != "" {
+		ms, err := ParseTime(from)
+		if err != nil {
+			return 0, 0, err
+		}
+		first = params.Frame(ms)
+	}
+	if to != "" {
+		ms, err := ParseTime(to)
+		if err != nil {
+			return 0, 0, err
+		}
+		last = params.Frame(ms)
+	}
+	if last < 0 {
+		return first, 0, nil
+	}
+	return first, last + 1, nil
+}
+
+// stillSink writes the only frame of a still to a file.
+type stillSink struct {
+	sinkError
+	path string
+	writer utils.Writer
+	written bool
+}
+
+// WriteFrame writes the frame to the path of the still.
+func (s *stillSink) WriteFrame(index int, image []byte) error {
+	if index != 0 {
+		return s.set(fmt.Errorf("a still has a single frame, got frame %d", index))
+	}
+	s.mu.Lock()
+	s.written = true
+	s.mu.Unlock()
+	return s.set(s.writer.WriteFile(s.path, image, 0644))
+}
+
+// Close fails if the frame wasn't written.
+func (s *stillSink) Close() error {
+	s.mu.Lock()
+	written := s.written
+	s.mu.Unlock()
+	if !written {
+		_ = s.set(errors.New("the still wasn't captured"))
+	}
+	return s.Err()
+}
+
+// still sets the params to capture the frame shown at the time, in ms, to the
+// output path.
+func still(params RecordParams, at float64, output string, writer utils.Writer) RecordParams {
+	params.StartFrame = params.Frame(at)
+	params.EndFrame = params.StartFrame + 1
+	params.Workers = 1
+	params.Sink = params.process(&stillSink{path: output, writer: writer})
+	return params
+}
+
+// Still captures a single frame of the animation, shown at the time in ms,
+// as a PNG image. The page seeks straight to the frame.
+func Still(params RecordParams, at float64, output string) error {
+	if at < 0 {
+		return fmt.Errorf("invalid time %.3fms", at)
+	}
+	if output == "" {
+		return errors.New("no output path was supplied")
+	}
+
+	// Create a context canceled by Ctrl+C
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	// Serve the page to capture
+	params, err := params.serve()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	params = still(params, at, output, utils.FWriter)
+	if _, err := record(ctx, params); err != nil {
+		abort(params.Sink, err)
+		return err
+	}
+	if err := params.Sink.Close(); err != nil {
+		return err
+	}
+	utils.Success("Still saved at " + output)
+	return nil
+}
@@ -0,0 +1,339 @@
+package chrome
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb"
+	"gux.codes/omega/pkg/browser"
+)
+
+// FrameError is returned when a frame couldn't be recorded.
+type FrameError struct {
+	// Frame is the index of the failing frame.
+	Frame int
+	// Op is the step that failed: navigate, seek, omega, ready, screenshot,
+	// blur, downscale or write.
+	Op string
+	Err error
+}
+
+// Error returns the error message, including the frame index.
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("frame %d: %s: %s", e.Frame, e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// worker records frames on its own browser context.
+type worker struct {
+	id int
+	// parent is the context of the whole recording.
+	parent context.Context
+	// stop is closed when the recording fails.
+	stop <-chan struct{}
+	params RecordParams
+	// ctx is the browser context, recreated if it crashes.
+	ctx context.Context
+	cancel context.CancelFunc
+	// closed is closed when the browser context is released.
+	closed chan struct{}
+	// current is the frame the browser context is showing.
+	current int
+	// clock is the time of the page in ms, or -1 if unknown.
+	clock float64
+	// protocol follows the Omega.js commands of the page, if not nil.
+	protocol *protocol
+	// unlisten removes the console listener of the browser context.
+	unlisten context.CancelFunc
+	mu sync.Mutex
+	// logged holds the Omega.js commands logged since they were last taken.
+	logged []Command
+	// followed is the last frame whose commands were taken, or -1.
+	followed int
+	stats RecordStats
+}
+
+// newWorker creates a worker with a browser context that shows the recording
+// URL, and reports the Omega.js commands of the page to protocol if it is not
+// nil. The browser context is canceled once stop is closed.
+func newWorker(parent context.Context, id int, params RecordParams, protocol *protocol, stop <-chan struct{}) (*worker, error) {
+	w := &worker{id: id, parent: parent, stop: stop, params: params, protocol: protocol}
+	if err := w.open(); err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// open creates a new browser context and navigates it to the recording URL.
+// A new browser context starts at frame 0.
+func (w *worker) open() error {
+	w.close()
+	ctx, cancel := w.params.Pool.get(w.parent)
+	closed := make(chan struct{})
+	w.ctx, w.cancel, w.closed = ctx, cancel, closed
+	w.current, w.clock, w.followed = 0, 0, -1
+	// Cancel any pending browser call if the recording fails
+	go func() {
+		select {
+		case <- w.stop:
+			cancel()
+		case <- closed:
+		}
+	}()
+	// Listen to the page before it loads, so no command is lost
+	if w.protocol != nil {
+		if err := w.listen(); err != nil {
+			return err
+		}
+	}
+	if err := w.params.clock().Prepare(w.ctx); err != nil {
+		return err
+	}
+	if err := browser.Chrome.Navigate(w.ctx, w.params.URL, w.params.viewport()); err != nil {
+		return err
+	}
+	return waitReady(w.ctx, w.params.Ready)
+}
+
+// listen collects the Omega.js commands logged by the page until the browser
+// context is released.
+func (w *worker) listen() error {
+	w.take()
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.unlisten = cancel
+	return browser.Chrome.OnConsole(ctx, func(message string) {
+		if command, ok := ParseCommand(message); ok {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.logged = append(w.logged, command)
+		}
+	})
+}
+
+// take returns the Omega.js commands logged since they were last taken.
+func (w *worker) take() []Command {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	commands := w.logged
+	w.logged = nil
+	return commands
+}
+
+// follow reports the Omega.js commands logged since they were last taken as
+// the commands of the frame f.
+func (w *worker) follow(f int) error {
+	if w.protocol == nil {
+		return nil
+	}
+	w.followed = f
+	return w.protocol.report(f, w.take())
+}
+
+// close releases the browser context, or returns it to the pool. Pooled
+// browser contexts stop listening to the console.
+func (w *worker) close() {
+	if w.unlisten != nil {
+		w.unlisten()
+		w.unlisten = nil
+	}
+	if w.cancel != nil {
+		close(w.closed)
+		w.params.Pool.put(w.ctx, w.cancel, reusable(w.params.clock()))
+		w.cancel = nil
+	}
+}
+
+// stopped reports whether the recording was canceled or failed.
+func (w *worker) stopped() bool {
+	select {
+	case <- w.stop:
+		return true
+	default:
+		return w.parent.Err() != nil
+	}
+}
+
+// crashed reports whether the browser context ended while the recording goes
+// on.
+func (w *worker) crashed() bool {
+	return w.ctx.Err() != nil && !w.stopped()
+}
+
+// run records the frames handed out by the scheduler until there are none
+// left, and returns the first error it can't recover from.
+func (w *worker) run(scheduler *scheduler, frames FrameSink, bar *pb.ProgressBar) error {
+	for {
+		if err := w.parent.Err(); err != nil {
+			return err
+		}
+		f, ok := scheduler.take(w.id)
+		if !ok {
+			// Steal frames from the slowest worker, if any
+			if !scheduler.steal(w.id) {
+				return nil
+			}
+			w.stats.Steals++
+			continue
+		}
+		frame, err := w.capture(f)
+		if err != nil {
+			return err
+		}
+		// Store screenshot. Frames are numbered from the start of the range.
+		t := time.Now()
+		if err := frames.WriteFrame(f - scheduler.start, frame); err != nil {
+			return &FrameError{Frame: f, Op: "write", Err: err}
+		}
+		w.stats.WriteTime += time.Since(t)
+		w.stats.Frames++
+		// Update the bar
+		bar.Increment()
+	}
+}
+
+// capture takes the screenshot of a frame, retrying up to params.Retries
+// times. A browser context that crashed is recreated before retrying.
+func (w *worker) capture(f int) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		frame, err := w.try(f)
+		if err == nil {
+			return frame, nil
+		}
+		if attempt >= w.params.Retries || w.stopped() {
+			return nil, err
+		}
+		// The frame shown by the browser context is unknown after an error, so
+		// the next attempt seeks to the frame again.
+		w.current, w.clock, w.followed = -1, -1, -1
+		if w.crashed() {
+			if err := w.open(); err != nil {
+				return nil, &FrameError{Frame: f, Op: "navigate", Err: err}
+			}
+		}
+	}
+}
+
+// seek moves the clock of the page to ms. Pages whose clock can't go back are
+// loaded again to start over.
+func (w *worker) seek(ms float64) error {
+	clock := w.params.clock()
+	if !clock.Rewinds() && (w.clock < 0 || ms < w.clock) {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	if ms == w.clock {
+		return nil
+	}
+	t := time.Now()
+	if err := clock.Seek(w.ctx, w.clock, ms); err != nil {
+		w.clock = -1
+		return err
+	}
+	w.stats.EvaluateTime += time.Since(t)
+	w.stats.Evaluations++
+	w.clock = ms
+	return nil
+}
+
+// moveTo seeks the page to the frame f. When following the Omega.js commands,
+// a page that didn't show the frame before f seeks to it first, dropping the
+// commands logged on the way, so only the commands logged while moving to f
+// are reported for it.
+func (w *worker) moveTo(f int) error {
+	if w.protocol != nil && f > 0 && w.followed != f - 1 {
+		if err := w.seek(w.params.frameTime(f - 1)); err != nil {
+			return err
+		}
+		w.take()
+		w.followed = f - 1
+	}
+	return w.seek(w.params.frameTime(f))
+}
+
+// ready waits for the ready conditions before a screenshot, when they are
+// checked on each frame.
+func (w *worker) ready() error {
+	if !w.params.ReadyEachFrame {
+		return nil
+	}
+	return waitReady(w.ctx, w.params.Ready)
+}
+
+// try seeks the browser context to the frame and takes its screenshot.
+func (w *worker) try(f int) ([]byte, error) {
+	if w.params.MotionBlur.enabled() {
+		return w.blur(f)
+	}
+	// Seek or advance the clock to the frame
+	if f != w.current {
+		if f != w.current + 1 {
+			w.stats.Seeks++
+		}
+		if err := w.moveTo(f); err != nil {
+			return nil, &FrameError{Frame: f, Op: "seek", Err: err}
+		}
+		w.current = f
+	}
+	if err := w.follow(f); err != nil {
+		return nil, &FrameError{Frame: f, Op: "omega", Err: err}
+	}
+	if err := w.ready(); err != nil {
+		return nil, &FrameError{Frame: f, Op: "ready", Err: err}
+	}
+	// Take screenshot
+	t := time.Now()
+	frame, err := browser.Chrome.Screenshot(w.ctx, w.params.screenshotOptions())
+	if err != nil {
+		return nil, &FrameError{Frame: f, Op: "screenshot", Err: err}
+	}
+	w.stats.ScreenshotTime += time.Since(t)
+	return frame, nil
+}
+
+// blur takes a screenshot of each motion blur sample of the frame and
+// averages them.
+func (w *worker) blur(f int) ([]byte, error) {
+	blur := w.params.MotionBlur
+	start := w.params.frameTime(f)
+	samples := make([][]byte, 0, blur.Samples)
+	// The page is left between frames, so the next frame seeks again
+	w.current = -1
+	for i := 0; i < blur.Samples; i++ {
+		// The first sample shows the frame itself. The commands logged while
+		// moving to the other samples are reported with the next frame.
+		if i == 0 {
+			if err := w.moveTo(f); err != nil {
+				return nil, &FrameError{Frame: f, Op: "seek", Err: err}
+			}
+			if err := w.follow(f); err != nil {
+				return nil, &FrameError{Frame: f, Op: "omega", Err: err}
+			}
+		} else if err := w.seek(start + blur.offset(i, w.params.frameDuration())); err != nil {
+			return nil, &FrameError{Frame: f, Op: "seek", Err: err}
+		}
+		if err := w.ready(); err != nil {
+			return nil, &FrameError{Frame: f, Op: "ready", Err: err}
+		}
+
+		t := time.Now()
+		sample, err := browser.Chrome.Screenshot(w.ctx, w.params.screenshotOptions())
+		if err != nil {
+			return nil, &FrameError{Frame: f, Op: "screenshot", Err: err}
+		}
+		w.stats.ScreenshotTime += time.Since(t)
+		samples = append(samples, sample)
+	}
+	frame, err := Average(samples)
+	if err != nil {
+		return nil, &FrameError{Frame: f, Op: "blur", Err: err}
+	}
+	return frame, nil
+}
@@ -0,0 +1,159 @@
+package chrome
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	mbrowser "gux.codes/omega/mocks/browser"
+	"gux.codes/omega/pkg/browser"
+)
+
+type WorkerSuite struct {
+	suite.Suite
+	handler *mbrowser.BrowserHandler
+	mu sync.Mutex
+	times *pageTimes
+	// attempts counts the screenshots taken of each time.
+	attempts map[string]int
+	// cancels holds the cancel function of each browser context.
+	cancels []context.CancelFunc
+}
+
+// screenshotError is returned by the mocked Screenshot when a frame fails.
+var screenshotError = errors.New("screenshot failed")
+
+// setup mocks a browser whose screenshots fail while fail returns true.
+func (suite *WorkerSuite) setup(fail func(ctx context.Context, script string, attempt int) bool) {
+	suite.handler = &mbrowser.BrowserHandler{}
+	suite.times = &pageTimes{}
+	suite.attempts = make(map[string]int)
+	suite.cancels = nil
+
+	suite.handler.On("NewContext", mock.Anything).Return(
+		func(parent context.Context) context.Context {
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			ctx, cancel := context.WithCancel(context.WithValue(parent, workerKey{}, len(suite.cancels)))
+			suite.cancels = append(suite.cancels, cancel)
+			return ctx
+		},
+		func(parent context.Context) context.CancelFunc {
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			return suite.cancels[len(suite.cancels) - 1]
+		},
+	)
+	suite.handler.On("Evaluate", mock.Anything, mock.AnythingOfType("string")).Return(suite.times.evaluate, nil)
+	suite.handler.On("Screenshot", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, options browser.ScreenshotOptions) []byte {
+			return []byte("frame\n")
+		},
+		func(ctx context.Context, options browser.ScreenshotOptions) error {
+			script := suite.times.time(ctx)
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			suite.attempts[script]++
+			if fail(ctx, script, suite.attempts[script]) {
+				return screenshotError
+			}
+			return nil
+		},
+	)
+	mockBrowser(suite.handler, nil)
+}
+
+func (suite *WorkerSuite) TearDownTest() {
+	browser.Chrome = browser.ChromeBrowser{}
+}
+
+func (suite *WorkerSuite) TestFrameError() {
+	suite.Run("should return the failing frame after every retry", func() {
+		suite.setup(func(ctx context.Context, script string, attempt int) bool {
+			return script == goTo(10)
+		})
+		var output bytes.Buffer
+		params := RecordParams{
+			Duration: 1000,
+			Sink    : NewOrderedWriter(&output, REORDER_FRAMES_PER_WORKER),
+			Workers : 1,
+			Retries : 2,
+		}
+		_, err := record(context.Background(), params)
+
+		var frameError *FrameError
+		suite.True(errors.As(err, &frameError))
+		suite.Equal(10, frameError.Frame)
+		suite.Equal("screenshot", frameError.Op)
+		suite.True(errors.Is(err, screenshotError))
+		suite.Equal(3, suite.attempts[goTo(10)])
+		suite.Equal(10, bytes.Count(output.Bytes(), []byte("\n")))
+	})
+
+	suite.Run("should stop every worker when one of them fails", func() {
+		suite.setup(func(ctx context.Context, script string, attempt int) bool {
+			return script == goTo(30)
+		})
+		params := RecordParams{
+			Duration: 1000,
+			Sink    : NewOrderedWriter(&bytes.Buffer{}, 4 * REORDER_FRAMES_PER_WORKER),
+			Workers : 4,
+		}
+		_, err := record(context.Background(), params)
+
+		var frameError *FrameError
+		suite.True(errors.As(err, &frameError))
+		suite.Equal(30, frameError.Frame)
+	})
+}
+
+func (suite *WorkerSuite) TestRetries() {
+	suite.Run("should retry a frame that failed", func() {
+		suite.setup(func(ctx context.Context, script string, attempt int) bool {
+			return script == goTo(5) && attempt == 1
+		})
+		params := RecordParams{
+			Duration: 1000,
+			Sink    : NewOrderedWriter(&bytes.Buffer{}, REORDER_FRAMES_PER_WORKER),
+			Workers : 1,
+			Retries : 1,
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(60, stats.Frames)
+		suite.Equal(2, suite.attempts[goTo(5)])
+		suite.handler.AssertNumberOfCalls(suite.T(), "NewContext", 1)
+	})
+
+	suite.Run("should recreate a browser context that crashed", func() {
+		suite.setup(func(ctx context.Context, script string, attempt int) bool {
+			if script != goTo(5) || attempt > 1 {
+				return false
+			}
+			// Simulate a crash of the browser context
+			suite.cancels[ctx.Value(workerKey{}).(int)]()
+			return true
+		})
+		params := RecordParams{
+			Duration: 1000,
+			Sink    : NewOrderedWriter(&bytes.Buffer{}, REORDER_FRAMES_PER_WORKER),
+			Workers : 1,
+			Retries : 1,
+		}
+		stats, err := record(context.Background(), params)
+		suite.NoError(err)
+		suite.Equal(60, stats.Frames)
+		suite.handler.AssertNumberOfCalls(suite.T(), "NewContext", 2)
+		// The new browser context should seek back to the frame
+		suite.Equal(1, stats.Seeks)
+	})
+}
+
+// Run the test suite
+func TestWorkerSuite(t *testing.T) {
+	suite.Run(t, new(WorkerSuite))
+}
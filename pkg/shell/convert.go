@@ -0,0 +1,326 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recording formats supported by Convert.
+const (
+	// FormatOmega is omega's own YAML recording format.
+	FormatOmega = "omega"
+	// FormatTtyrec is the binary format written by `ttyrec`.
+	FormatTtyrec = "ttyrec"
+	// FormatScript is the typescript plus timing file pair written by
+	// util-linux `script --timing`.
+	FormatScript = "script"
+)
+
+// scriptTimeLayout is the layout of the dates on the typescript header and
+// footer lines.
+const scriptTimeLayout = "2006-01-02 15:04:05-07:00"
+
+// ConvertOptions modify the way a recording is converted.
+type ConvertOptions struct {
+	// From is the format of the input recording. Detected from the input
+	// path extension if empty.
+	From string
+	// To is the format of the output recording. Detected from the output path
+	// extension if empty.
+	To string
+	// Timing is the path of the input timing file of a `script` recording.
+	Timing string
+	// OutputTiming is the path of the output timing file of a `script`
+	// recording.
+	OutputTiming string
+}
+
+// FormatFromPath guesses the format of a recording from its path extension.
+func FormatFromPath(path string) (string, error) {
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml", ".gz":
+		return FormatOmega, nil
+	case ".ttyrec", ".tty":
+		return FormatTtyrec, nil
+	case ".typescript", ".script":
+		return FormatScript, nil
+	}
+
+	return "", fmt.Errorf("can't detect the recording format of %s", path)
+}
+
+// timingPath returns the timing file path of a `script` recording, which
+// defaults to the typescript path followed by `.timing`.
+func timingPath(path, timing string) string {
+	if timing != "" {
+		return timing
+	}
+
+	return path + ".timing"
+}
+
+// Convert reads the recording at inputPath and writes it at outputPath in the
+// format selected by the options.
+func Convert(inputPath, outputPath string, options ConvertOptions) error {
+	var err error
+
+	// Detect the formats that weren't provided
+	if options.From == "" {
+		if options.From, err = FormatFromPath(inputPath); err != nil {
+			return err
+		}
+	}
+	if options.To == "" {
+		if options.To, err = FormatFromPath(outputPath); err != nil {
+			return err
+		}
+	}
+
+	// Import the recording
+	var records []Record
+	switch options.From {
+	case FormatOmega:
+		records, err = ReadRecording(inputPath)
+	case FormatTtyrec:
+		records, err = readFile(inputPath, ReadTtyrec)
+	case FormatScript:
+		records, err = readScriptFiles(inputPath, timingPath(inputPath, options.Timing))
+	default:
+		err = errors.New("unknown input format: " + options.From)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Export the recording
+	start := time.Now()
+	switch options.To {
+	case FormatOmega:
+		var file bytes.Buffer
+		if err := EncodeRecording(&file, records, IsCompressedPath(outputPath)); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(outputPath, file.Bytes(), 0644)
+	case FormatTtyrec:
+		var file bytes.Buffer
+		if err := WriteTtyrec(&file, records, start); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(outputPath, file.Bytes(), 0644)
+	case FormatScript:
+		var typescript, timing bytes.Buffer
+		if err := WriteScript(&typescript, &timing, records, start); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(outputPath, typescript.Bytes(), 0644); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(timingPath(outputPath, options.OutputTiming), timing.Bytes(), 0644)
+	}
+
+	return errors.New("unknown output format: " + options.To)
+}
+
+// readFile opens the file at path and decodes it with read.
+func readFile(path string, read func(io.Reader) ([]Record, error)) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return read(file)
+}
+
+// readScriptFiles opens a typescript and its timing file and decodes them.
+func readScriptFiles(typescriptPath, timingPath string) ([]Record, error) {
+	typescript, err := os.Open(typescriptPath)
+	if err != nil {
+		return nil, err
+	}
+	defer typescript.Close()
+	timing, err := os.Open(timingPath)
+	if err != nil {
+		return nil, err
+	}
+	defer timing.Close()
+
+	return ReadScript(typescript, timing)
+}
+
+// checkSinglePane returns an error if the records belong to a multi-pane
+// recording, which other formats can't represent.
+func checkSinglePane(records []Record) error {
+	for _, record := range records {
+		if record.Pane != 0 {
+			return errors.New("multi-pane recordings can only be stored in the omega format")
+		}
+	}
+
+	return nil
+}
+
+// ttyrecHeader is the header that precedes each chunk of a ttyrec file.
+type ttyrecHeader struct {
+	Sec  uint32
+	Usec uint32
+	Len  uint32
+}
+
+// ReadTtyrec decodes a ttyrec file into records.
+func ReadTtyrec(reader io.Reader) ([]Record, error) {
+	records := make([]Record, 0)
+	var previous time.Duration
+
+	for {
+		var header ttyrecHeader
+		if err := binary.Read(reader, binary.LittleEndian, &header); err == io.EOF {
+			break
+		} else if err != nil {
+			return records, fmt.Errorf("invalid ttyrec header: %w", err)
+		}
+		content := make([]byte, header.Len)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return records, fmt.Errorf("invalid ttyrec chunk: %w", err)
+		}
+		// ttyrec stores absolute timestamps, while records store the delay
+		// from the last record.
+		timestamp := time.Duration(header.Sec) * time.Second + time.Duration(header.Usec) * time.Microsecond
+		delay := 0
+		if len(records) > 0 {
+			delay = int((timestamp - previous) / time.Millisecond)
+		}
+		previous = timestamp
+		records = append(records, Record{Delay: delay, Content: string(content)})
+	}
+
+	return records, nil
+}
+
+// WriteTtyrec encodes the records as a ttyrec file whose first chunk is
+// timestamped at start.
+func WriteTtyrec(writer io.Writer, records []Record, start time.Time) error {
+	if err := checkSinglePane(records); err != nil {
+		return err
+	}
+	timestamp := start
+	for _, record := range records {
+		timestamp = timestamp.Add(time.Duration(record.Delay) * time.Millisecond)
+		header := ttyrecHeader{
+			Sec:  uint32(timestamp.Unix()),
+			Usec: uint32(timestamp.Nanosecond() / 1000),
+			Len:  uint32(len(record.Content)),
+		}
+		if err := binary.Write(writer, binary.LittleEndian, header); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, record.Content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadScript decodes a typescript and its timing file, as written by
+// `script --timing`, into records. Both the classic and the advanced timing
+// formats are supported.
+func ReadScript(typescript, timing io.Reader) ([]Record, error) {
+	records := make([]Record, 0)
+	content := bufio.NewReader(typescript)
+
+	// Skip the "Script started on..." header line
+	if header, err := content.Peek(len("Script started")); err == nil && string(header) == "Script started" {
+		if _, err := content.ReadString('\n'); err != nil {
+			return records, err
+		}
+	}
+
+	var delay float64
+	scanner := bufio.NewScanner(timing)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// Advanced timing files prefix each entry with its type. Only output
+		// entries are stored in the typescript.
+		entry := "O"
+		if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+			entry, fields = fields[0], fields[1:]
+		}
+		if len(fields) < 2 {
+			return records, fmt.Errorf("invalid timing entry on line %d", line)
+		}
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return records, fmt.Errorf("invalid delay on line %d: %w", line, err)
+		}
+		delay += seconds
+		if entry != "O" {
+			continue
+		}
+		length, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return records, fmt.Errorf("invalid length on line %d: %w", line, err)
+		}
+		chunk := make([]byte, length)
+		if _, err := io.ReadFull(content, chunk); err != nil {
+			return records, fmt.Errorf("typescript is shorter than its timing file: %w", err)
+		}
+		// The first record always starts right away.
+		record := Record{Content: string(chunk)}
+		if len(records) > 0 {
+			record.Delay = int(math.Round(delay * 1000))
+		}
+		delay = 0
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// WriteScript encodes the records as a typescript and a classic timing file,
+// so they can be played with `scriptreplay`.
+func WriteScript(typescript, timing io.Writer, records []Record, start time.Time) error {
+	if err := checkSinglePane(records); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(typescript, "Script started on %s\n", start.Format(scriptTimeLayout)); err != nil {
+		return err
+	}
+
+	delay := 0
+	end := start
+	for _, record := range records {
+		delay += record.Delay
+		end = end.Add(time.Duration(record.Delay) * time.Millisecond)
+		// Empty chunks can't be represented on a timing file, so their delay is
+		// carried to the next one.
+		if len(record.Content) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(timing, "%.6f %d\n", float64(delay) / 1000, len(record.Content)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(typescript, record.Content); err != nil {
+			return err
+		}
+		delay = 0
+	}
+
+	_, err := fmt.Fprintf(typescript, "\nScript done on %s\n", end.Format(scriptTimeLayout))
+
+	return err
+}
@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConvertSuite struct {
+	records []Record
+	start time.Time
+	suite.Suite
+}
+
+func (suite *ConvertSuite) SetupSuite() {
+	suite.records = []Record{
+		{Delay: 0, Content: "$ "},
+		{Delay: 500, Content: "ls\r\n"},
+		{Delay: 750, Content: "a  b\r\n$ "},
+	}
+	suite.start = time.Date(2021, 5, 3, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *ConvertSuite) fixture(name string) []byte {
+	content, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	return content
+}
+
+func (suite *ConvertSuite) TestTtyrec() {
+	suite.Run("should import ttyrec files", func() {
+		records, err := ReadTtyrec(bytes.NewReader(suite.fixture("session.ttyrec")))
+		suite.NoError(err)
+		suite.Equal(suite.records, records)
+	})
+
+	suite.Run("should export ttyrec files", func() {
+		var file bytes.Buffer
+		suite.NoError(WriteTtyrec(&file, suite.records, time.Unix(1620000000, 0)))
+		suite.Equal(suite.fixture("session.ttyrec"), file.Bytes())
+	})
+
+	suite.Run("should fail on truncated ttyrec files", func() {
+		content := suite.fixture("session.ttyrec")
+		_, err := ReadTtyrec(bytes.NewReader(content[:len(content) - 1]))
+		suite.Error(err)
+	})
+}
+
+func (suite *ConvertSuite) TestScript() {
+	suite.Run("should import typescript and timing files", func() {
+		records, err := ReadScript(bytes.NewReader(suite.fixture("session.typescript")), bytes.NewReader(suite.fixture("session.timing")))
+		suite.NoError(err)
+		suite.Equal(suite.records, records)
+	})
+
+	suite.Run("should import advanced timing files", func() {
+		timing := "H 0.000000 START_TIME 2021-05-03 00:00:00\nO 0.000000 2\nI 0.250000 3\nO 0.250000 4\nO 0.750000 8\n"
+		records, err := ReadScript(bytes.NewReader(suite.fixture("session.typescript")), strings.NewReader(timing))
+		suite.NoError(err)
+		suite.Equal(suite.records, records)
+	})
+
+	suite.Run("should export typescript and timing files", func() {
+		var typescript, timing bytes.Buffer
+		suite.NoError(WriteScript(&typescript, &timing, suite.records, suite.start))
+		suite.Equal(string(suite.fixture("session.typescript")), typescript.String())
+		suite.Equal(string(suite.fixture("session.timing")), timing.String())
+	})
+}
+
+func (suite *ConvertSuite) TestConvert() {
+	outputPath := "/tmp/converted.yml"
+	defer os.RemoveAll(outputPath)
+
+	suite.NoError(Convert("testdata/session.ttyrec", outputPath, ConvertOptions{}))
+	records, err := ReadRecording(outputPath)
+	suite.NoError(err)
+	suite.Equal(suite.records, records)
+
+	// should fail if the format can't be detected
+	suite.Error(Convert("testdata/session.ttyrec", "/tmp/converted", ConvertOptions{}))
+}
+
+// Run the test suite
+func TestConvertSuite(t *testing.T) {
+	suite.Run(t, new(ConvertSuite))
+}
@@ -0,0 +1,153 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// separator is drawn between two panes.
+const separator = "│"
+
+// pane is a Screen placed on a Layout.
+type pane struct {
+	id int
+	screen *Screen
+	// left is the terminal column where the pane starts.
+	left int
+}
+
+// Layout draws several Screens side by side on a terminal, one pane per
+// Screen, sorted by their pane ID.
+type Layout struct {
+	mu sync.Mutex
+	writer io.Writer
+	panes []*pane
+	// focus is the ID of the pane that holds the cursor.
+	focus int
+}
+
+// NewLayout creates an empty Layout that draws on writer.
+func NewLayout(writer io.Writer) *Layout {
+	return &Layout{writer: writer}
+}
+
+// find returns the pane with the provided ID, or nil if it doesn't exist.
+func (l *Layout) find(id int) *pane {
+	for _, p := range l.panes {
+		if p.id == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// Resize adds a pane of the provided size to the layout, or resizes it if it
+// already exists, and redraws the whole layout.
+func (l *Layout) Resize(id, cols, rows int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.resize(id, cols, rows)
+}
+
+// resize adds or resizes a pane and redraws the whole layout.
+func (l *Layout) resize(id, cols, rows int) error {
+	if p := l.find(id); p != nil {
+		p.screen.Resize(cols, rows)
+	} else {
+		l.panes = append(l.panes, &pane{id: id, screen: NewScreen(cols, rows)})
+		sort.Slice(l.panes, func(i, j int) bool { return l.panes[i].id < l.panes[j].id })
+	}
+
+	// Place each pane after the previous one and its separator
+	left := 0
+	for _, p := range l.panes {
+		p.left = left
+		cols, _ := p.screen.Size()
+		left += cols + 1
+	}
+
+	return l.draw()
+}
+
+// Write feeds the pty output of a pane and draws the rows that changed. Panes
+// that weren't added with Resize are created with a default size.
+func (l *Layout) Write(id int, input []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.find(id) == nil {
+		if err := l.resize(id, 80, 24); err != nil {
+			return err
+		}
+	}
+
+	p := l.find(id)
+	p.screen.Write(input)
+	var buf bytes.Buffer
+	if err := p.screen.Render(&buf, p.left, 0); err != nil {
+		return err
+	}
+	l.cursor(&buf)
+	_, err := l.writer.Write(buf.Bytes())
+	return err
+}
+
+// Focus moves the cursor to the pane with the provided ID.
+func (l *Layout) Focus(id int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.focus = id
+	var buf bytes.Buffer
+	l.cursor(&buf)
+	_, err := l.writer.Write(buf.Bytes())
+	return err
+}
+
+// Draw clears the terminal and draws every pane.
+func (l *Layout) Draw() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.draw()
+}
+
+// draw clears the terminal and draws every pane and separator.
+func (l *Layout) draw() error {
+	// There is nothing to draw until a pane is added
+	if len(l.panes) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\033[0m\033[2J")
+	rows := 0
+	for _, p := range l.panes {
+		p.screen.touchAll()
+		if err := p.screen.Render(&buf, p.left, 0); err != nil {
+			return err
+		}
+		if _, r := p.screen.Size(); r > rows {
+			rows = r
+		}
+	}
+	// Draw a separator after every pane but the last one
+	for _, p := range l.panes[:len(l.panes) - 1] {
+		cols, _ := p.screen.Size()
+		for y := 0; y < rows; y++ {
+			fmt.Fprintf(&buf, "\033[%d;%dH%s", y + 1, p.left + cols + 1, separator)
+		}
+	}
+	l.cursor(&buf)
+	_, err := l.writer.Write(buf.Bytes())
+	return err
+}
+
+// cursor moves the cursor to its position on the focused pane.
+func (l *Layout) cursor(buf *bytes.Buffer) {
+	p := l.find(l.focus)
+	if p == nil {
+		return
+	}
+	x, y := p.screen.Cursor()
+	fmt.Fprintf(buf, "\033[%d;%dH", y + 1, p.left + x + 1)
+}
@@ -1,16 +1,16 @@
 package shell
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/fatih/color"
-	"gopkg.in/yaml.v3"
 )
 
 // PlayOptions modify the way the recording is played.
@@ -40,19 +40,23 @@ func NewPlayOptions() PlayOptions {
 
 // ReadRecording reads a recording from a file and returns its contents.
 func ReadRecording(recordingPath string) ([]Record, error) {
-	var records []Record
-	// Check if the config exists at `configPath`
-	if _, err := os.Stat(recordingPath); err != nil {
-		return records, errors.New("Can't find a file at: " + recordingPath)
-	}
-	// Open the configuration file
-	configFile, err := ioutil.ReadFile(recordingPath)
+	records := make([]Record, 0)
+	// Open the recording file
+	file, err := OpenRecording(recordingPath)
 	if err != nil {
 		return records, err
 	}
-	// Unmarshall the configuration file
-	if err := yaml.Unmarshal(configFile, &records); err != nil {
-		return records, err
+	defer file.Close()
+	// Decode every record of the recording file
+	decoder := NewRecordDecoder(file)
+	for {
+		var record Record
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, record)
 	}
 
 	return records, nil
@@ -64,64 +68,153 @@ func AdjustFrameDelay(records []Record, options PlayOptions) []Record {
 	modifiedRecords := make([]Record, 0)
 
 	for _, record := range records {
-		if options.FrameDelay != -1 {
-			record.Delay = options.FrameDelay
-		}
+		modifiedRecords = append(modifiedRecords, AdjustRecordDelay(record, options))
+	}
 
-		if (options.MaxIdleTime != -1 && options.MaxIdleTime < record.Delay) {
-			record.Delay = options.MaxIdleTime
-		}
+	return modifiedRecords
+}
 
-		record.Delay = int(float64(record.Delay) * options.SpeedFactor)
+// AdjustRecordDelay adjusts the delay of a single record according to the
+// provided options.
+func AdjustRecordDelay(record Record, options PlayOptions) Record {
+	if options.FrameDelay != -1 {
+		record.Delay = options.FrameDelay
+	}
 
-		modifiedRecords = append(modifiedRecords, record)
+	if (options.MaxIdleTime != -1 && options.MaxIdleTime < record.Delay) {
+		record.Delay = options.MaxIdleTime
 	}
 
-	return modifiedRecords
+	record.Delay = int(float64(record.Delay) * options.SpeedFactor)
+
+	return record
 }
 
 
-func Play (recordingPath string, options PlayOptions) error {
-	// Parse the recording file
-	records, err := ReadRecording(recordingPath)
+// Clock controls the passage of time during playback.
+type Clock interface {
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is a Clock that uses the system time.
+type realClock struct{}
+
+// After exposes the time.After function
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// RealClock is the Clock used by default to play recordings.
+var RealClock Clock = realClock{}
+
+// Player plays recordings on any io.Writer.
+type Player struct {
+	// Output is where the records are written.
+	Output io.Writer
+	// Clock is used to wait between records.
+	Clock Clock
+	// Options modify the way the recording is played.
+	Options PlayOptions
+}
+
+// NewPlayer creates a Player that writes on output using the system time.
+func NewPlayer(output io.Writer, options PlayOptions) *Player {
+	return &Player{
+		Output: output,
+		Clock: RealClock,
+		Options: options,
+	}
+}
+
+// Play plays the recording read from reader until it ends or the context is
+// canceled. Gzip compressed recordings are detected automatically, and records
+// are decoded as they are played, so long recordings start right away.
+func (p *Player) Play(ctx context.Context, reader io.Reader) error {
+	reader, err := NewRecordingReader(reader)
 	if err != nil {
 		return err
 	}
+	decoder := NewRecordDecoder(reader)
+
+	var layout *Layout
+	for {
+		var record Record
+		if err := decoder.Decode(&record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// Modify the delay between records according to FramDelayOptions
+		record = AdjustRecordDelay(record, p.Options)
+		if layout, err = playRecord(p.Output, layout, record); err != nil {
+			return err
+		}
+		select {
+		case <- ctx.Done():
+			return ctx.Err()
+		case <- p.Clock.After(time.Duration(record.Delay) * time.Millisecond):
+		}
+	}
+}
 
-	// Modify the delay between records according to FramDelayOptions
-	records = AdjustFrameDelay(records, options)
+// Play plays the recording stored at recordingPath on the terminal.
+func Play (recordingPath string, options PlayOptions) error {
+	// Open the recording file
+	file, err := OpenRecording(recordingPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
 	if !options.Silent {
 		showPlaybackMessage(recordingPath, options)
 	}
 
 	// Capture the interrupt and kill signals
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-
-	// Interrupt channel
-	interrupt := make(chan bool)
-
-	go func() {
-		Clear()
-		for _, record := range records {
-			fmt.Printf(record.Content)
-			time.Sleep(time.Duration(record.Delay) * time.Millisecond)
-		}
-		interrupt <- true
-	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Block
-	select {
-	case <- signals:
-	case <- interrupt:
+	Clear()
+	err = NewPlayer(os.Stdout, options).Play(ctx, file)
+	if errors.Is(err, context.Canceled) {
+		err = nil
 	}
 
 	if !options.Silent {
 		showDoneMessage()
 	}
 
-	return nil
+	return err
+}
+
+// playRecord writes the content of a record on writer. Multi-pane recordings
+// are drawn through a Layout, created as soon as a record sets the size of a
+// pane or belongs to a pane other than the first one.
+func playRecord(writer io.Writer, layout *Layout, record Record) (*Layout, error) {
+	if layout == nil && (record.Cols > 0 || record.Pane != 0) {
+		layout = NewLayout(writer)
+	}
+	if layout == nil {
+		_, err := io.WriteString(writer, record.Content)
+		return nil, err
+	}
+
+	if record.Cols > 0 {
+		if err := layout.Resize(record.Pane, record.Cols, record.Rows); err != nil {
+			return layout, err
+		}
+	}
+	if record.Content == "" {
+		return layout, nil
+	}
+	// The cursor follows the pane being played
+	if err := layout.Focus(record.Pane); err != nil {
+		return layout, err
+	}
+
+	return layout, layout.Write(record.Pane, []byte(record.Content))
 }
 
 func showPlaybackMessage(recordingPath string, options PlayOptions) {
@@ -2,9 +2,11 @@ package shell
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v3"
@@ -105,6 +107,56 @@ func (suite *PlaySuite) TestAdjustFrameDelay() {
 	})
 }
 
+func (suite *PlaySuite) TestPlayRecord() {
+	suite.Run("should write single pane records as they are", func() {
+		var output bytes.Buffer
+		layout, err := playRecord(&output, nil, Record{Content: "\033[2Jhi"})
+		suite.NoError(err)
+		suite.Nil(layout)
+		suite.Equal("\033[2Jhi", output.String())
+	})
+
+	suite.Run("should draw multi-pane records on a layout", func() {
+		var output bytes.Buffer
+		var layout *Layout
+		var err error
+		for _, record := range []Record{{Pane: 0, Cols: 4, Rows: 1}, {Pane: 1, Cols: 4, Rows: 1}, {Pane: 1, Content: "b"}} {
+			layout, err = playRecord(&output, layout, record)
+			suite.NoError(err)
+		}
+		suite.NotNil(layout)
+		suite.Contains(output.String(), "\033[1;6Hb")
+	})
+}
+
+// fakeClock is a Clock that returns right away, keeping track of the
+// requested durations.
+type fakeClock struct {
+	durations []time.Duration
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.durations = append(c.durations, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func (suite *PlaySuite) TestPlayer() {
+	var output bytes.Buffer
+	clock := &fakeClock{}
+	player := NewPlayer(&output, suite.playOptions)
+	player.Clock = clock
+
+	file, err := os.Open(suite.recordingPath)
+	suite.NoError(err)
+	defer file.Close()
+
+	suite.NoError(player.Play(context.Background(), file))
+	suite.Equal("01", output.String())
+	suite.Equal([]time.Duration{0, time.Millisecond}, clock.durations)
+}
+
 // Run the test suite
 func TestPlaySuite(t *testing.T) {
 	suite.Run(t, new(PlaySuite))
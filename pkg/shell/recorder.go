@@ -0,0 +1,346 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/creack/pty"
+)
+
+// RecordSink stores the records of a finished recording.
+type RecordSink interface {
+	WriteRecords(records []Record) error
+}
+
+// FileSink stores the records as a YAML recording file. The file is gzip
+// compressed if its path ends in `.gz`.
+type FileSink struct {
+	// Path of the recording file.
+	Path string
+}
+
+// WriteRecords writes the records to the recording file.
+func (sink FileSink) WriteRecords(records []Record) error {
+	var file bytes.Buffer
+
+	// Encode the Recording, compressing it if needed
+	if err := EncodeRecording(&file, records, IsCompressedPath(sink.Path)); err != nil {
+		return err
+	}
+
+	// Write the recording file
+	return ioutil.WriteFile(sink.Path, file.Bytes(), 0644)
+}
+
+// MemorySink keeps the records in memory.
+type MemorySink struct {
+	Records []Record
+}
+
+// WriteRecords stores the records in the sink.
+func (sink *MemorySink) WriteRecords(records []Record) error {
+	sink.Records = append(sink.Records, records...)
+	return nil
+}
+
+// Recorder records pty sessions. It reads the keyboard input from Input, draws
+// the sessions on Output, and stores the records on Sink when the sessions
+// end. Recording a single specification copies the pty output to Output as
+// is, while several specifications are laid out side by side as panes.
+type Recorder struct {
+	// Input is copied to the pty of the focused pane.
+	Input io.Reader
+	// Output is where the sessions are drawn.
+	Output io.Writer
+	// Sink stores the records when the sessions end.
+	Sink RecordSink
+	// Cols and Rows hold the size of the terminal the sessions are drawn on.
+	// The pty keeps its default size if they are zero.
+	Cols int
+	Rows int
+
+	mu sync.Mutex
+	// inputOnce starts the goroutine that reads Input into chunks.
+	inputOnce sync.Once
+	chunks chan []byte
+	specifications []ShellSpecification
+	ptys []*os.File
+	writer *ShellWriter
+	layout *Layout
+}
+
+// NewRecorder creates a Recorder for a terminal of the provided size.
+func NewRecorder(input io.Reader, output io.Writer, sink RecordSink, cols, rows int) *Recorder {
+	return &Recorder{
+		Input: input,
+		Output: output,
+		Sink: sink,
+		Cols: cols,
+		Rows: rows,
+	}
+}
+
+// paneSizes returns the size of the pty of each pane. Panes share the width
+// of the terminal, unless their specification sets a custom size.
+func (r *Recorder) paneSizes() [][2]int {
+	count := len(r.specifications)
+	cols := r.Cols
+	if count > 1 {
+		cols = (r.Cols - count + 1) / count
+	}
+	sizes := make([][2]int, count)
+	for i, specification := range r.specifications {
+		sizes[i] = [2]int{cols, r.Rows}
+		if specification.Cols != -1 {
+			sizes[i][0] = specification.Cols
+		}
+		if specification.Rows != -1 {
+			sizes[i][1] = specification.Rows
+		}
+	}
+	return sizes
+}
+
+// track keeps track of the size of a pane, drawing it on the layout of
+// multi-pane sessions.
+func (r *Recorder) track(pane, cols, rows int) error {
+	if r.layout == nil {
+		r.writer.Track(pane, cols, rows)
+		return nil
+	}
+	r.writer.Resize(pane, cols, rows)
+	return r.layout.Resize(pane, cols, rows)
+}
+
+// Record runs a pty for each specification until every command exits or the
+// context is canceled, and stores the records on the Sink. The recording
+// settings, like MinDelay, are taken from the first specification.
+func (r *Recorder) Record(ctx context.Context, specifications ...ShellSpecification) error {
+	if len(specifications) == 0 {
+		return errors.New("no shell specification was provided")
+	}
+
+	r.mu.Lock()
+	r.specifications = specifications
+	r.writer = NewShellWriter(&specifications[0])
+	r.layout = nil
+	if len(specifications) > 1 {
+		r.layout = NewLayout(r.Output)
+	}
+	r.ptys = make([]*os.File, 0, len(specifications))
+	commands := make([]*exec.Cmd, 0, len(specifications))
+	// Stop the commands already started if one of them fails
+	cleanup := func() {
+		for i, c := range commands {
+			_ = c.Process.Kill()
+			_ = r.ptys[i].Close()
+		}
+	}
+
+	// Start each command with a pty
+	for i, size := range r.paneSizes() {
+		c := command(specifications[i])
+		var ptmx *os.File
+		var err error
+		if size[0] > 0 && size[1] > 0 {
+			ptmx, err = pty.StartWithSize(c, &pty.Winsize{Cols: uint16(size[0]), Rows: uint16(size[1])})
+		} else {
+			ptmx, err = pty.Start(c)
+		}
+		if err != nil {
+			cleanup()
+			r.mu.Unlock()
+			return err
+		}
+		// Keep the command before tracking it, so the cleanup stops it too
+		commands = append(commands, c)
+		r.ptys = append(r.ptys, ptmx)
+		if size[0] > 0 && size[1] > 0 {
+			if err := r.track(i, size[0], size[1]); err != nil {
+				cleanup()
+				r.mu.Unlock()
+				return err
+			}
+		}
+	}
+	writer, layout, ptys := r.writer, r.layout, r.ptys
+	r.mu.Unlock()
+
+	// Copy the input to the focused pty until the recording stops
+	stopped := make(chan struct{})
+	var forwarding sync.WaitGroup
+	forwarding.Add(1)
+	go func() {
+		defer forwarding.Done()
+		forwardInput(pauseReader{&inputReader{chunks: r.inputChunks(), stopped: stopped}, writer}, ptys, layout)
+	}()
+
+	// stop ends the recording: it kills the commands, stops forwarding the
+	// input and releases the ptys. It runs once, when the context is canceled
+	// or the commands exit.
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			for _, c := range commands {
+				_ = c.Process.Kill()
+			}
+			close(stopped)
+			forwarding.Wait()
+			for _, ptmx := range ptys {
+				_ = ptmx.Close()
+			}
+		})
+	}
+
+	// Stop every command when the context is canceled
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <- ctx.Done():
+			stop()
+		case <- done:
+		}
+	}()
+
+	// Copy each pty to the output and the writer
+	var wg sync.WaitGroup
+	for i, ptmx := range ptys {
+		wg.Add(1)
+		go func(pane int, ptmx *os.File) {
+			defer wg.Done()
+			buf := make([]byte, 32 * 1024)
+			for {
+				n, err := ptmx.Read(buf)
+				if n > 0 {
+					writer.Pane(pane).Write(buf[:n])
+					if layout == nil {
+						_, _ = r.Output.Write(buf[:n])
+					} else if err := layout.Write(pane, buf[:n]); err != nil {
+						log.Printf("error drawing pane %d: %s", pane, err)
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}(i, ptmx)
+	}
+	wg.Wait()
+
+	// Reap the processes, then release the ptys
+	for _, c := range commands {
+		_ = c.Wait()
+	}
+	close(done)
+	stop()
+
+	// Store the records
+	if err := writer.Flush(r.Sink); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// Resize changes the size of the terminal the sessions are drawn on, resizing
+// the ptys that don't have a custom size.
+func (r *Recorder) Resize(cols, rows int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Cols, r.Rows = cols, rows
+	for i, size := range r.paneSizes() {
+		if i >= len(r.ptys) {
+			break
+		}
+		if err := pty.Setsize(r.ptys[i], &pty.Winsize{Cols: uint16(size[0]), Rows: uint16(size[1])}); err != nil {
+			return err
+		}
+		if err := r.track(i, size[0], size[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inputChunks returns the chunks read from Input. Input is read by a single
+// goroutine for the life of the Recorder, so input that arrives between two
+// recordings goes to the next one instead of a pty that was closed.
+func (r *Recorder) inputChunks() <-chan []byte {
+	r.inputOnce.Do(func() {
+		r.chunks = make(chan []byte)
+		go func() {
+			defer close(r.chunks)
+			for {
+				buf := make([]byte, 1024)
+				n, err := r.Input.Read(buf)
+				if n > 0 {
+					r.chunks <- buf[:n]
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	})
+	return r.chunks
+}
+
+// inputReader reads the input chunks of a recording until it stops.
+type inputReader struct {
+	chunks <-chan []byte
+	stopped <-chan struct{}
+	pending []byte
+}
+
+// Read returns the next chunk of input, or io.EOF once the recording stops or
+// the input ends.
+func (r *inputReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		select {
+		case <- r.stopped:
+			return 0, io.EOF
+		case chunk, ok := <- r.chunks:
+			if !ok {
+				return 0, io.EOF
+			}
+			r.pending = chunk
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// forwardInput copies the input to the pty of the focused pane. On
+// multi-pane sessions the focus moves to the next pane every time FocusKey is
+// pressed.
+func forwardInput(input io.Reader, ptys []*os.File, layout *Layout) {
+	focus := 0
+	buf := make([]byte, 1024)
+	for {
+		n, err := input.Read(buf)
+		if err != nil {
+			return
+		}
+		start := 0
+		for i := 0; i < n && len(ptys) > 1; i++ {
+			if buf[i] != FocusKey {
+				continue
+			}
+			_, _ = ptys[focus].Write(buf[start:i])
+			focus = (focus + 1) % len(ptys)
+			_ = layout.Focus(focus)
+			start = i + 1
+		}
+		_, _ = ptys[focus].Write(buf[start:n])
+	}
+}
@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RecorderSuite struct {
+	specification ShellSpecification
+	suite.Suite
+}
+
+func (suite *RecorderSuite) SetupSuite() {
+	suite.specification = *NewShellSpecification()
+	suite.specification.Command = "/bin/cat"
+}
+
+// content joins the content of every record.
+func content(records []Record) string {
+	var content strings.Builder
+	for _, record := range records {
+		content.WriteString(record.Content)
+	}
+	return content.String()
+}
+
+func (suite *RecorderSuite) TestRecord() {
+	var output bytes.Buffer
+	sink := &MemorySink{}
+	// cat echoes the input until it gets an EOF (Ctrl+D)
+	recorder := NewRecorder(strings.NewReader("hello\n\x04"), &output, sink, 80, 24)
+
+	suite.NoError(recorder.Record(context.Background(), suite.specification))
+	suite.Contains(output.String(), "hello")
+	suite.Contains(content(sink.Records), "hello")
+}
+
+func (suite *RecorderSuite) TestRecordPanes() {
+	var output bytes.Buffer
+	sink := &MemorySink{}
+	// The input goes to the first pane until FocusKey moves it to the second
+	input := strings.NewReader("first\n\x04" + string(FocusKey) + "second\n\x04")
+	recorder := NewRecorder(input, &output, sink, 81, 24)
+
+	suite.NoError(recorder.Record(context.Background(), suite.specification, suite.specification))
+	// Starting the second pane can take a few ms
+	sink.Records[1].Delay = 0
+	suite.Equal(Record{Pane: 0, Cols: 40, Rows: 24}, sink.Records[0])
+	suite.Equal(Record{Pane: 1, Cols: 40, Rows: 24}, sink.Records[1])
+	panes := map[int][]Record{}
+	for _, record := range sink.Records[2:] {
+		panes[record.Pane] = append(panes[record.Pane], record)
+	}
+	suite.Contains(content(panes[0]), "first")
+	suite.NotContains(content(panes[0]), "second")
+	suite.Contains(content(panes[1]), "second")
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func (suite *RecorderSuite) TestRecordLayoutError() {
+	// The layout of the panes can't be drawn on the output
+	recorder := NewRecorder(strings.NewReader(""), failingWriter{}, &MemorySink{}, 81, 24)
+
+	suite.EqualError(recorder.Record(context.Background(), suite.specification, suite.specification), "write failed")
+	// The command started before the error should be stopped
+	suite.Len(recorder.ptys, 1)
+	_, err := recorder.ptys[0].Write([]byte("hello\n"))
+	suite.True(errors.Is(err, os.ErrClosed))
+}
+
+func (suite *RecorderSuite) TestRecordCancel() {
+	// The input never ends, so cat only stops when the context is canceled
+	input, _ := io.Pipe()
+	recorder := NewRecorder(input, &bytes.Buffer{}, &MemorySink{}, 80, 24)
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+
+	suite.Equal(context.DeadlineExceeded, recorder.Record(ctx, suite.specification))
+}
+
+func (suite *RecorderSuite) TestRecordAgain() {
+	input, keyboard := io.Pipe()
+	sink := &MemorySink{}
+	recorder := NewRecorder(input, &bytes.Buffer{}, sink, 80, 24)
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+	suite.Equal(context.DeadlineExceeded, recorder.Record(ctx, suite.specification))
+
+	// The input typed after the first recording should reach the next one
+	go keyboard.Write([]byte("next\n\x04"))
+	ctx, cancel = context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	sink.Records = nil
+	suite.NoError(recorder.Record(ctx, suite.specification))
+	suite.Contains(content(sink.Records), "next")
+}
+
+// Run the test suite
+func TestRecorderSuite(t *testing.T) {
+	suite.Run(t, new(RecorderSuite))
+}
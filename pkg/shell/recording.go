@@ -0,0 +1,179 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gzipMagic holds the first two bytes of every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsCompressedPath reports whether a recording stored at path should be gzip
+// compressed.
+func IsCompressedPath(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// recordingFile is an io.ReadCloser that closes every layer of the recording
+// reader, like the gzip reader and the underlying file.
+type recordingFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes every layer of the recording file.
+func (f *recordingFile) Close() error {
+	var err error
+	for _, closer := range f.closers {
+		if cerr := closer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewRecordingReader returns a reader over the YAML content of a recording.
+// Gzip compressed recordings are detected by their magic bytes and
+// decompressed on the fly.
+func NewRecordingReader(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	// Peek at the magic bytes without consuming them
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return buffered, nil
+	}
+
+	return gzip.NewReader(buffered)
+}
+
+// OpenRecording opens the recording stored at recordingPath and returns a
+// reader over its YAML content.
+func OpenRecording(recordingPath string) (io.ReadCloser, error) {
+	// Check if the recording exists at `recordingPath`
+	if _, err := os.Stat(recordingPath); err != nil {
+		return nil, errors.New("Can't find a file at: " + recordingPath)
+	}
+	file, err := os.Open(recordingPath)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewRecordingReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// Close the gzip reader, if any, before the file.
+	closers := []io.Closer{file}
+	if closer, ok := reader.(io.Closer); ok {
+		closers = []io.Closer{closer, file}
+	}
+
+	return &recordingFile{reader, closers}, nil
+}
+
+// EncodeRecording writes the records as YAML to the writer. The output is gzip
+// compressed if `compress` is true.
+func EncodeRecording(writer io.Writer, records []Record, compress bool) error {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(writer)
+		writer = gz
+	}
+
+	// Create a custom YAML encoder
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+
+	// Marshall to YAML the Recording struct
+	if err := encoder.Encode(records); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if gz != nil {
+		return gz.Close()
+	}
+
+	return nil
+}
+
+// RecordDecoder reads the Records of a recording one at a time, so they can be
+// played before the whole file has been parsed.
+type RecordDecoder struct {
+	reader *bufio.Reader
+	// next holds the first line of the next sequence item.
+	next []byte
+	// queue holds records decoded but not yet returned.
+	queue []Record
+	eof bool
+}
+
+// NewRecordDecoder creates a RecordDecoder that reads from reader.
+func NewRecordDecoder(reader io.Reader) *RecordDecoder {
+	return &RecordDecoder{reader: bufio.NewReader(reader)}
+}
+
+// isItemStart reports whether a line opens a new item of a top level YAML
+// sequence.
+func isItemStart(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("- ")) || bytes.Equal(bytes.TrimRight(line, "\r\n"), []byte("-"))
+}
+
+// chunk reads the lines of the next top level sequence item. Every line that
+// doesn't start a new item belongs to the current one, so block scalars are
+// kept together.
+func (d *RecordDecoder) chunk() ([]byte, error) {
+	chunk := d.next
+	d.next = nil
+	for !d.eof {
+		line, err := d.reader.ReadBytes('\n')
+		if err == io.EOF {
+			d.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		if len(chunk) > 0 && isItemStart(line) {
+			d.next = line
+			break
+		}
+		chunk = append(chunk, line...)
+	}
+
+	return chunk, nil
+}
+
+// Decode stores the next Record of the recording in record. It returns io.EOF
+// when there are no more records.
+func (d *RecordDecoder) Decode(record *Record) error {
+	for len(d.queue) == 0 {
+		if d.eof && d.next == nil {
+			return io.EOF
+		}
+		chunk, err := d.chunk()
+		if err != nil {
+			return err
+		}
+		// Each chunk is a valid YAML sequence on its own. Recordings that
+		// don't use block sequences are read as a single chunk.
+		if err := yaml.Unmarshal(chunk, &d.queue); err != nil {
+			return err
+		}
+	}
+
+	*record = d.queue[0]
+	d.queue = d.queue[1:]
+
+	return nil
+}
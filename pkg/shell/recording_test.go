@@ -0,0 +1,98 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RecordingSuite struct {
+	recordingPath string
+	records []Record
+	suite.Suite
+}
+
+func (suite *RecordingSuite) cleanup() {
+	// Make sure there are no existing files
+	if err := os.RemoveAll(suite.recordingPath); err != nil {
+		suite.FailNow(err.Error())
+	}
+}
+
+func (suite *RecordingSuite) SetupSuite() {
+	suite.recordingPath = "/tmp/recording.yml.gz"
+	suite.records = []Record{
+		{Delay: 0, Content: "$ ls\r\n"},
+		{Delay: 10, Content: "a\nb\n- c\n"},
+		{Delay: 20, Content: "\033[0m-"},
+	}
+}
+
+func (suite *RecordingSuite) SetupTest() {
+	suite.cleanup()
+}
+
+func (suite *RecordingSuite) TearDownSuite() {
+	suite.cleanup()
+}
+
+func (suite *RecordingSuite) TestCompressedRecording() {
+	specification := NewShellSpecification()
+	specification.OutputPath = suite.recordingPath
+	writer := NewShellWriter(specification)
+	writer.records = suite.records
+
+	// should compress recordings whose path ends in .gz
+	suite.NoError(writer.Dump())
+	content, err := ioutil.ReadFile(suite.recordingPath)
+	suite.NoError(err)
+	suite.Equal(gzipMagic, content[:2])
+
+	// should detect and decompress the recording when reading it
+	records, err := ReadRecording(suite.recordingPath)
+	suite.NoError(err)
+	suite.Equal(suite.records, records)
+}
+
+func (suite *RecordingSuite) TestRecordDecoder() {
+	decode := func(reader io.Reader) []Record {
+		records := make([]Record, 0)
+		decoder := NewRecordDecoder(reader)
+		for {
+			var record Record
+			err := decoder.Decode(&record)
+			if err == io.EOF {
+				return records
+			}
+			suite.NoError(err)
+			records = append(records, record)
+		}
+	}
+
+	suite.Run("should decode block sequences record by record", func() {
+		var file bytes.Buffer
+		suite.NoError(EncodeRecording(&file, suite.records, false))
+		suite.Equal(suite.records, decode(&file))
+	})
+
+	suite.Run("should decode flow sequences", func() {
+		file := strings.NewReader(`[{delay: 0, content: "a"}, {delay: 1, content: "b"}]`)
+		suite.Equal([]Record{{Delay: 0, Content: "a"}, {Delay: 1, Content: "b"}}, decode(file))
+	})
+
+	suite.Run("should decode empty recordings", func() {
+		var file bytes.Buffer
+		suite.NoError(EncodeRecording(&file, []Record{}, false))
+		suite.Equal([]Record{}, decode(&file))
+	})
+}
+
+// Run the test suite
+func TestRecordingSuite(t *testing.T) {
+	suite.Run(t, new(RecordingSuite))
+}
@@ -0,0 +1,491 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cell is a single character of a Screen.
+type cell struct {
+	// Char holds the character shown on the cell.
+	char rune
+	// Style holds the SGR parameters active when the character was written.
+	style string
+}
+
+// blank is an empty cell.
+var blank = cell{' ', ""}
+
+// Parser states of a Screen.
+const (
+	stateGround = iota
+	stateEscape
+	stateCharset
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// Screen is a minimal VT100 terminal emulator. It keeps track of what a pty
+// session looks like, so it can be drawn anywhere on another terminal.
+type Screen struct {
+	cols, rows int
+	cells [][]cell
+	// dirty marks the rows that changed since the last render.
+	dirty []bool
+	// Cursor position
+	x, y int
+	savedX, savedY int
+	// wrap is set when the last character was written on the last column.
+	wrap bool
+	// Scroll region
+	top, bottom int
+	// Current SGR parameters
+	style string
+	// Escape sequence parser state
+	state int
+	params []byte
+	// pending holds the bytes of an incomplete UTF-8 character.
+	pending []byte
+}
+
+// NewScreen creates a blank Screen of the provided size.
+func NewScreen(cols, rows int) *Screen {
+	screen := &Screen{}
+	screen.Resize(cols, rows)
+	return screen
+}
+
+// Size returns the number of columns and rows of the screen.
+func (s *Screen) Size() (int, int) {
+	return s.cols, s.rows
+}
+
+// Resize changes the size of the screen keeping its content.
+func (s *Screen) Resize(cols, rows int) {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	cells := make([][]cell, rows)
+	for y := range cells {
+		cells[y] = make([]cell, cols)
+		for x := range cells[y] {
+			cells[y][x] = blank
+			if y < s.rows && x < s.cols {
+				cells[y][x] = s.cells[y][x]
+			}
+		}
+	}
+	s.cols, s.rows = cols, rows
+	s.cells = cells
+	s.dirty = make([]bool, rows)
+	s.top, s.bottom = 0, rows - 1
+	s.x, s.y = clamp(s.x, 0, cols - 1), clamp(s.y, 0, rows - 1)
+	s.savedX, s.savedY = clamp(s.savedX, 0, cols - 1), clamp(s.savedY, 0, rows - 1)
+	s.touchAll()
+}
+
+// Cursor returns the position of the cursor.
+func (s *Screen) Cursor() (int, int) {
+	return s.x, s.y
+}
+
+// Write feeds the output of a pty session to the screen.
+func (s *Screen) Write(input []byte) (int, error) {
+	data := append(s.pending, input...)
+	s.pending = nil
+	for len(data) > 0 {
+		b := data[0]
+		// Printable characters can span multiple bytes
+		if s.state == stateGround && b >= 0x80 {
+			if !utf8.FullRune(data) {
+				s.pending = append([]byte{}, data...)
+				break
+			}
+			r, size := utf8.DecodeRune(data)
+			s.put(r)
+			data = data[size:]
+			continue
+		}
+		s.feed(b)
+		data = data[1:]
+	}
+
+	return len(input), nil
+}
+
+// feed handles a single byte of the pty output.
+func (s *Screen) feed(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.escape(b)
+		return
+	case stateCharset:
+		s.state = stateGround
+		return
+	case stateCSI:
+		if b >= 0x40 && b <= 0x7e {
+			s.state = stateGround
+			s.csi(b, string(s.params))
+			return
+		}
+		s.params = append(s.params, b)
+		return
+	case stateOSC:
+		if b == 0x07 {
+			s.state = stateGround
+		} else if b == 0x1b {
+			s.state = stateOSCEscape
+		}
+		return
+	case stateOSCEscape:
+		s.state = stateGround
+		return
+	}
+
+	switch b {
+	case 0x1b:
+		s.state = stateEscape
+	case '\r':
+		s.x, s.wrap = 0, false
+	case '\n', 0x0b, 0x0c:
+		s.lineFeed()
+	case '\b':
+		if s.x > 0 {
+			s.x--
+		}
+		s.wrap = false
+	case '\t':
+		s.x, s.wrap = clamp((s.x / 8 + 1) * 8, 0, s.cols - 1), false
+	default:
+		if b >= 0x20 {
+			s.put(rune(b))
+		}
+	}
+}
+
+// escape handles the byte that follows an ESC.
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.state, s.params = stateCSI, s.params[:0]
+	case ']', 'P', '_', '^':
+		// Operating system commands and device control strings are skipped
+		// until their terminator.
+		s.state = stateOSC
+	case '(', ')', '*', '+', '#':
+		s.state = stateCharset
+	case '7':
+		s.savedX, s.savedY = s.x, s.y
+	case '8':
+		s.x, s.y = s.savedX, s.savedY
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.x = 0
+		s.lineFeed()
+	case 'M':
+		if s.y == s.top {
+			s.scrollDown(1)
+		} else if s.y > 0 {
+			s.y--
+		}
+	case 'c':
+		s.style = ""
+		s.x, s.y = 0, 0
+		s.top, s.bottom = 0, s.rows - 1
+		s.erase(0, 0, s.cols, s.rows)
+	}
+}
+
+// csi handles a Control Sequence Introducer sequence.
+func (s *Screen) csi(final byte, params string) {
+	// Private sequences, like showing or hiding the cursor, are ignored
+	// except for the alternate screen buffer, which starts with a clean screen.
+	if strings.HasPrefix(params, "?") {
+		if (final == 'h' || final == 'l') && (params == "?1049" || params == "?47" || params == "?1047") {
+			s.erase(0, 0, s.cols, s.rows)
+		}
+		return
+	}
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+	s.wrap = false
+
+	switch final {
+	case 'A':
+		s.y = clamp(s.y - arg(0, 1), 0, s.rows - 1)
+	case 'B':
+		s.y = clamp(s.y + arg(0, 1), 0, s.rows - 1)
+	case 'C':
+		s.x = clamp(s.x + arg(0, 1), 0, s.cols - 1)
+	case 'D':
+		s.x = clamp(s.x - arg(0, 1), 0, s.cols - 1)
+	case 'E':
+		s.x, s.y = 0, clamp(s.y + arg(0, 1), 0, s.rows - 1)
+	case 'F':
+		s.x, s.y = 0, clamp(s.y - arg(0, 1), 0, s.rows - 1)
+	case 'G', '`':
+		s.x = clamp(arg(0, 1) - 1, 0, s.cols - 1)
+	case 'd':
+		s.y = clamp(arg(0, 1) - 1, 0, s.rows - 1)
+	case 'H', 'f':
+		s.y = clamp(arg(0, 1) - 1, 0, s.rows - 1)
+		s.x = clamp(arg(1, 1) - 1, 0, s.cols - 1)
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			s.erase(s.x, s.y, s.cols, s.y + 1)
+			s.erase(0, s.y + 1, s.cols, s.rows)
+		case 1:
+			s.erase(0, 0, s.cols, s.y)
+			s.erase(0, s.y, s.x + 1, s.y + 1)
+		default:
+			s.erase(0, 0, s.cols, s.rows)
+		}
+	case 'K':
+		switch arg(0, 0) {
+		case 0:
+			s.erase(s.x, s.y, s.cols, s.y + 1)
+		case 1:
+			s.erase(0, s.y, s.x + 1, s.y + 1)
+		default:
+			s.erase(0, s.y, s.cols, s.y + 1)
+		}
+	case 'X':
+		s.erase(s.x, s.y, clamp(s.x + arg(0, 1), 0, s.cols), s.y + 1)
+	case 'P':
+		row := s.cells[s.y]
+		n := clamp(arg(0, 1), 0, s.cols - s.x)
+		copy(row[s.x:], row[s.x + n:])
+		s.erase(s.cols - n, s.y, s.cols, s.y + 1)
+	case '@':
+		row := s.cells[s.y]
+		n := clamp(arg(0, 1), 0, s.cols - s.x)
+		copy(row[s.x + n:], row[s.x:])
+		s.erase(s.x, s.y, s.x + n, s.y + 1)
+	case 'L', 'M':
+		if s.y < s.top || s.y > s.bottom {
+			return
+		}
+		top := s.top
+		s.top = s.y
+		if final == 'L' {
+			s.scrollDown(arg(0, 1))
+		} else {
+			s.scrollUp(arg(0, 1))
+		}
+		s.top = top
+	case 'S':
+		s.scrollUp(arg(0, 1))
+	case 'T':
+		s.scrollDown(arg(0, 1))
+	case 'r':
+		s.top = clamp(arg(0, 1) - 1, 0, s.rows - 1)
+		s.bottom = clamp(arg(1, s.rows) - 1, s.top, s.rows - 1)
+		s.x, s.y = 0, 0
+	case 's':
+		s.savedX, s.savedY = s.x, s.y
+	case 'u':
+		s.x, s.y = s.savedX, s.savedY
+	case 'm':
+		s.sgr(params)
+	}
+}
+
+// sgr updates the current style with a Select Graphic Rendition sequence.
+func (s *Screen) sgr(params string) {
+	if params == "" || params == "0" {
+		s.style = ""
+		return
+	}
+	// Everything before the last reset is irrelevant
+	if strings.HasPrefix(params, "0;") {
+		s.style = ""
+		params = params[2:]
+	}
+	if i := strings.LastIndex(params, ";0;"); i != -1 {
+		s.style = ""
+		params = params[i + 3:]
+	}
+	if s.style == "" {
+		s.style = params
+	} else {
+		s.style = s.style + ";" + params
+	}
+}
+
+// put writes a printable character at the cursor position.
+func (s *Screen) put(r rune) {
+	if s.wrap {
+		s.x, s.wrap = 0, false
+		s.lineFeed()
+	}
+	s.cells[s.y][s.x] = cell{r, s.style}
+	s.dirty[s.y] = true
+	if s.x == s.cols - 1 {
+		s.wrap = true
+	} else {
+		s.x++
+	}
+}
+
+// lineFeed moves the cursor down, scrolling the screen if needed.
+func (s *Screen) lineFeed() {
+	s.wrap = false
+	if s.y == s.bottom {
+		s.scrollUp(1)
+	} else if s.y < s.rows - 1 {
+		s.y++
+	}
+}
+
+// scrollUp scrolls the lines of the scroll region up n times.
+func (s *Screen) scrollUp(n int) {
+	n = clamp(n, 0, s.bottom - s.top + 1)
+	copy(s.cells[s.top:s.bottom + 1], s.cells[s.top + n:s.bottom + 1])
+	for y := s.bottom - n + 1; y <= s.bottom; y++ {
+		s.cells[y] = blankRow(s.cols)
+	}
+	s.touch(s.top, s.bottom + 1)
+}
+
+// scrollDown scrolls the lines of the scroll region down n times.
+func (s *Screen) scrollDown(n int) {
+	n = clamp(n, 0, s.bottom - s.top + 1)
+	copy(s.cells[s.top + n:s.bottom + 1], s.cells[s.top:s.bottom + 1 - n])
+	for y := s.top; y < s.top + n; y++ {
+		s.cells[y] = blankRow(s.cols)
+	}
+	s.touch(s.top, s.bottom + 1)
+}
+
+// erase blanks the cells between (x0, y0) and (x1, y1) row by row.
+func (s *Screen) erase(x0, y0, x1, y1 int) {
+	for y := clamp(y0, 0, s.rows); y < clamp(y1, 0, s.rows); y++ {
+		for x := clamp(x0, 0, s.cols); x < clamp(x1, 0, s.cols); x++ {
+			s.cells[y][x] = blank
+		}
+		s.dirty[y] = true
+	}
+}
+
+// touch marks the rows between y0 and y1 as dirty.
+func (s *Screen) touch(y0, y1 int) {
+	for y := y0; y < y1; y++ {
+		s.dirty[y] = true
+	}
+}
+
+// touchAll marks every row as dirty.
+func (s *Screen) touchAll() {
+	s.touch(0, s.rows)
+}
+
+// Render draws the rows that changed since the last render on writer, with
+// the top left corner of the screen at the provided terminal position.
+func (s *Screen) Render(writer io.Writer, left, top int) error {
+	var buf bytes.Buffer
+	for y := 0; y < s.rows; y++ {
+		if !s.dirty[y] {
+			continue
+		}
+		s.dirty[y] = false
+		fmt.Fprintf(&buf, "\033[%d;%dH", top + y + 1, left + 1)
+		s.renderRow(&buf, y)
+	}
+	_, err := writer.Write(buf.Bytes())
+	return err
+}
+
+// Repaint returns the escape sequences that draw the whole screen and put the
+// cursor back in place on a terminal of the same size.
+func (s *Screen) Repaint() string {
+	var buf bytes.Buffer
+	buf.WriteString("\033[0m\033[2J")
+	for y := 0; y < s.rows; y++ {
+		fmt.Fprintf(&buf, "\033[%d;1H", y + 1)
+		s.renderRow(&buf, y)
+	}
+	fmt.Fprintf(&buf, "\033[%d;%dH", s.y + 1, s.x + 1)
+	if s.style != "" {
+		fmt.Fprintf(&buf, "\033[%sm", s.style)
+	}
+	return buf.String()
+}
+
+// Text returns the characters shown on the screen, without styles or
+// trailing spaces.
+func (s *Screen) Text() string {
+	lines := make([]string, s.rows)
+	for y, row := range s.cells {
+		var line strings.Builder
+		for _, c := range row {
+			line.WriteRune(c.char)
+		}
+		lines[y] = strings.TrimRight(line.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderRow writes the cells of a row, including their styles.
+func (s *Screen) renderRow(buf *bytes.Buffer, y int) {
+	style := ""
+	for _, c := range s.cells[y] {
+		if c.style != style {
+			style = c.style
+			buf.WriteString("\033[0m")
+			if style != "" {
+				fmt.Fprintf(buf, "\033[%sm", style)
+			}
+		}
+		buf.WriteRune(c.char)
+	}
+	buf.WriteString("\033[0m")
+}
+
+// blankRow returns a row of blank cells.
+func blankRow(cols int) []cell {
+	row := make([]cell, cols)
+	for x := range row {
+		row[x] = blank
+	}
+	return row
+}
+
+// parseParams parses the numeric parameters of a CSI sequence.
+func parseParams(params string) []int {
+	args := make([]int, 0)
+	if params == "" {
+		return args
+	}
+	for _, param := range strings.Split(params, ";") {
+		n, _ := strconv.Atoi(param)
+		args = append(args, n)
+	}
+	return args
+}
+
+// clamp limits n to the [min, max] range.
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
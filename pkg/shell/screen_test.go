@@ -0,0 +1,83 @@
+package shell
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScreenSuite struct {
+	suite.Suite
+}
+
+func (suite *ScreenSuite) TestScreen() {
+	suite.Run("should write text and move the cursor", func() {
+		screen := NewScreen(10, 3)
+		screen.Write([]byte("$ ls\r\na  b"))
+		suite.Equal("$ ls\na  b\n", screen.Text())
+		x, y := screen.Cursor()
+		suite.Equal(4, x)
+		suite.Equal(1, y)
+	})
+
+	suite.Run("should wrap long lines and scroll", func() {
+		screen := NewScreen(4, 2)
+		screen.Write([]byte("abcdefg\r\nh"))
+		suite.Equal("efg\nh", screen.Text())
+	})
+
+	suite.Run("should handle cursor movement and erase sequences", func() {
+		screen := NewScreen(10, 3)
+		screen.Write([]byte("hello\r\nworld\033[1;2H\033[K\033[3;1Hxy\033[2D\033[1Pz"))
+		suite.Equal("h\nworld\nz", screen.Text())
+	})
+
+	suite.Run("should decode UTF-8 characters split between writes", func() {
+		screen := NewScreen(4, 1)
+		screen.Write([]byte("\xe2\x94"))
+		screen.Write([]byte("\x82Ω"))
+		suite.Equal("│Ω", screen.Text())
+	})
+
+	suite.Run("should keep the saved cursor inside a smaller screen", func() {
+		for _, save := range []string{"\0337", "\033[s"} {
+			restore := map[string]string{"\0337": "\0338", "\033[s": "\033[u"}[save]
+			screen := NewScreen(10, 5)
+			screen.Write([]byte("\033[5;10H" + save))
+			screen.Resize(4, 2)
+			screen.Write([]byte(restore + "x"))
+			x, y := screen.Cursor()
+			suite.Equal(3, x)
+			suite.Equal(1, y)
+			suite.Contains(screen.Text(), "x")
+		}
+	})
+
+	suite.Run("should skip operating system commands", func() {
+		screen := NewScreen(10, 1)
+		screen.Write([]byte("\033]0;title\007ok"))
+		suite.Equal("ok", screen.Text())
+	})
+}
+
+func (suite *ScreenSuite) TestLayout() {
+	var output bytes.Buffer
+	layout := NewLayout(&output)
+	// should not draw an empty layout
+	suite.NoError(layout.Draw())
+	suite.Empty(output.String())
+	suite.NoError(layout.Resize(0, 5, 2))
+	suite.NoError(layout.Resize(1, 5, 2))
+	suite.Contains(output.String(), "\033[1;6H" + separator)
+
+	// should draw the second pane after the first one and its separator
+	output.Reset()
+	suite.NoError(layout.Write(1, []byte("hi")))
+	suite.Contains(output.String(), "\033[1;7Hhi")
+}
+
+// Run the test suite
+func TestScreenSuite(t *testing.T) {
+	suite.Run(t, new(ScreenSuite))
+}
@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log"
 	"os"
@@ -8,7 +10,6 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/creack/pty"
 	"golang.org/x/term"
 )
 
@@ -18,6 +19,11 @@ type Record struct {
 	Delay int `yaml:"delay"`
   // Content of the record.
 	Content string `yaml:"content"`
+	// Pane identifies the pane of a multi-pane recording the record belongs to.
+	Pane int `yaml:"pane,omitempty"`
+	// Cols and Rows set the size of the pane when they are not zero.
+	Cols int `yaml:"cols,omitempty"`
+	Rows int `yaml:"rows,omitempty"`
 }
 
 // ShellSpecification dictates how the pty session will be recorded.
@@ -36,6 +42,8 @@ type ShellSpecification struct {
   Rows int
 	// OutputPath indicates the path where the recording will be saved.
 	OutputPath string
+	// PausePlaceholder inserts a "paused" frame where the recording was paused.
+	PausePlaceholder bool
 }
 
 // NewShellSpecification returns a default ShellSpecification.
@@ -51,8 +59,37 @@ func NewShellSpecification() *ShellSpecification {
 	}
 }
 
-// Shell runs a pty shell that will record stdout into a recordings file.
-func Shell(specification ShellSpecification) error {
+// FocusKey is the key that moves the keyboard focus to the next pane of a
+// multi-pane session (Ctrl+]).
+const FocusKey byte = 0x1d
+
+// PauseKey is the key that pauses and resumes the recording (Ctrl+\).
+const PauseKey byte = 0x1c
+
+// pauseReader intercepts PauseKey from the input and toggles the pause state
+// of the writer.
+type pauseReader struct {
+	reader io.Reader
+	writer *ShellWriter
+}
+
+// Read reads from the input, removing every PauseKey.
+func (r pauseReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b == PauseKey {
+			r.writer.TogglePause()
+			continue
+		}
+		p[kept] = b
+		kept++
+	}
+	return kept, err
+}
+
+// command creates the command described by the specification.
+func command(specification ShellSpecification) *exec.Cmd {
 	// Create a command
 	c := exec.Command(specification.Command)
 
@@ -62,64 +99,71 @@ func Shell(specification ShellSpecification) error {
 	// Modify the Current Working Directory of the command.
 	c.Dir = specification.Cwd
 
-	// Start command with a pty
-	ptmx, err := pty.Start(c)
+	return c
+}
+
+// Shell runs a pty shell that will record stdout into a recordings file.
+func Shell(specification ShellSpecification) error {
+	return MultiShell([]ShellSpecification{specification})
+}
+
+// MultiShell runs a pty for each specification and lays them out side by
+// side as panes. The output of every pane is recorded under its pane ID into
+// the recording file of the first specification. The keyboard focus moves to
+// the next pane with FocusKey, and PauseKey pauses the recording of every
+// pane.
+func MultiShell(specifications []ShellSpecification) error {
+	if len(specifications) == 0 {
+		return errors.New("no shell specification was provided")
+	}
+
+	// Stop recording on termination signals
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	// Get the size of the terminal
+	cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
 		return err
 	}
 
-	// Make sure the pty closes at the end
-	defer func() { _ = ptmx.Close() }()
+	// Create the Recorder
+	sink := FileSink{specifications[0].OutputPath}
+	recorder := NewRecorder(os.Stdin, os.Stdout, sink, cols, rows)
 
-	// Listen to the Signal Windows Change to redraw the window.
+	// Listen to the Signal Windows Change to resize the ptys.
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGWINCH)
-
+	defer signal.Stop(ch)
 	go func() {
 		for range ch {
-			// If Rows and Cols are defined then resize the window.
-			if specification.Rows != -1 && specification.Cols != -1 {
-				err := pty.Setsize(ptmx, &pty.Winsize{Rows: uint16(specification.Rows), Cols: uint16(specification.Cols)})
-				// If an error occurred print it and let the window inherit sdtin size.
-				if err != nil {
-					log.Printf("error applying custom size to pty: %s", err)
-				} else {
-					break
-				}
+			cols, rows, err := term.GetSize(int(os.Stdin.Fd()))
+			if err != nil {
+				log.Printf("error getting the terminal size: %s", err)
+				continue
 			}
-			// Set the pty window to the same size as stdin.
-			if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
+			if err := recorder.Resize(cols, rows); err != nil {
 				log.Printf("error resizing pty: %s", err)
 			}
 		}
 	}()
 
- 	// Initial resize
-	ch <- syscall.SIGWINCH
-
 	// Set stdin in raw mode
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	if err != nil {
-		panic(err)
+		return err
 	}
 	// Restore the old state of stdin when done.
 	defer func() { _ = term.Restore(int(os.Stdin.Fd()), oldState) }()
 
-	// Create a RecordWriter
-	writer := NewShellWriter(&specification)
-
-	// Create a MultiWriter
-	multi := io.MultiWriter(writer, os.Stdout)
-
-	// Copy stdin to the pty and the pty to stdout and writer
-	go func() { _, _ = io.Copy(ptmx, os.Stdin) }()
-	if _, err = io.Copy(multi, ptmx); err != nil {
-		panic(err)
+	// Record until every command exits
+	err = recorder.Record(ctx, specifications...)
+	if len(specifications) > 1 {
+		Clear()
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
 	}
 
-	defer func() {
-		writer.Dump()
-	}()
-
-	return nil
-}
\ No newline at end of file
+	return err
+}
@@ -1,11 +1,10 @@
 package shell
 
 import (
-	"bytes"
-	"io/ioutil"
+	"io"
+	"sort"
+	"sync"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
 // RecordWriter writes inputs as Records
@@ -13,14 +12,29 @@ type ShellWriter struct {
 	specification *ShellSpecification
 	timestamp time.Time
 	records []Record
+	// mu serializes the writes of concurrent panes.
+	mu sync.Mutex
+	// paused stops the input from being recorded.
+	paused bool
+	// screens keep track of what each pane looks like, so they can be
+	// repainted when the recording resumes.
+	screens map[int]*Screen
 }
 
+// PausedPlaceholder is the content of the frame inserted where the recording
+// was paused.
+const PausedPlaceholder = "\033[0m\033[2J\033[H[paused]"
+
+// PausedDelay is the time in ms the paused placeholder is shown on playback.
+const PausedDelay = 1000
+
 // NewShellWriter creates a new default ShellWriter.
 func NewShellWriter(specification *ShellSpecification) *ShellWriter {
 	return &ShellWriter{
 		specification: specification,
 		timestamp: time.Now(),
 		records: make([]Record, 0),
+		screens: make(map[int]*Screen),
 	}
 }
 
@@ -29,55 +43,166 @@ func (writer *ShellWriter) now() {
 	writer.timestamp = time.Now()
 }
 
+// delay returns the time in ms since the last write, or 0 for the first
+// record.
+func (writer *ShellWriter) delay() int {
+	if len(writer.records) == 0 {
+		return 0
+	}
+	return int(time.Since(writer.timestamp) / 1000 / 1000)
+}
+
 // Write the input as a new Record. If the time since the last Record is less
 // than MIN_DELAY, then it modifies the last record appending the new bytes.
 func (writer *ShellWriter) Write(input []byte) (int, error) {
-	// Calculate the delay since the last record
-	var delay int
+	return writer.write(0, input)
+}
 
-	defer writer.now()
+// write stores the input of a pane as a new Record, or appends it to the
+// previous record if it belongs to the same pane and the delay is less than
+// MIN_DELAY.
+func (writer *ShellWriter) write(pane int, input []byte) (int, error) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
 
-	if len(writer.records) == 0 {
-		record := &Record{0, string(input)}
-		writer.records = append(writer.records, *record)
+	// Keep the screen of the pane up to date, even while paused.
+	if screen, ok := writer.screens[pane]; ok {
+		screen.Write(input)
+	}
+	if writer.paused {
 		return len(input), nil
 	}
 
+	defer writer.now()
+
 	// If the delay is less than MIN_DELAY then we get the previous record
 	// and update it. Else we create a new one.
-	delay = int(time.Since(writer.timestamp) / 1000 / 1000)
-	if delay < writer.specification.MinDelay {
+	delay := writer.delay()
+	if len(writer.records) > 0 && delay < writer.specification.MinDelay {
 		previous := &writer.records[len(writer.records) - 1]
-		previous.Content = previous.Content + string(input)
-	} else {
-		record := &Record{delay, string(input)}
-		writer.records = append(writer.records, *record)
+		if previous.Pane == pane && previous.Cols == 0 {
+			previous.Content = previous.Content + string(input)
+			return len(input), nil
+		}
 	}
+	writer.records = append(writer.records, Record{Delay: delay, Content: string(input), Pane: pane})
 
 	// Comply with the Writer interface
 	return len(input), nil
 }
 
-// Dump writes the Recording to a YAML file on the path provided
-// by the shell specification.
-func (writer ShellWriter) Dump() error {
-	var file bytes.Buffer
-
-	// Create a custom YAML encoder
-	encoder := yaml.NewEncoder(&file)
-	encoder.SetIndent(2)
-
-	// Marshall to YAML the Recording struct
-	err := encoder.Encode(writer.records)
-	if err != nil {
-		return err
+// paneWriter writes the output of a single pane into a ShellWriter.
+type paneWriter struct {
+	writer *ShellWriter
+	pane int
+}
+
+// Write stores the input as a Record of the pane.
+func (w paneWriter) Write(input []byte) (int, error) {
+	return w.writer.write(w.pane, input)
+}
+
+// Pane returns a writer that stores its input as Records of the pane with the
+// provided ID.
+func (writer *ShellWriter) Pane(pane int) io.Writer {
+	return paneWriter{writer, pane}
+}
+
+// Resize stores a Record that sets the size of a pane.
+func (writer *ShellWriter) Resize(pane, cols, rows int) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	defer writer.now()
+
+	writer.track(pane, cols, rows)
+	writer.records = append(writer.records, Record{Delay: writer.delay(), Pane: pane, Cols: cols, Rows: rows})
+}
+
+// Track keeps track of the screen of a pane of the provided size, without
+// recording its size.
+func (writer *ShellWriter) Track(pane, cols, rows int) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	writer.track(pane, cols, rows)
+}
+
+// track creates or resizes the screen of a pane.
+func (writer *ShellWriter) track(pane, cols, rows int) {
+	if screen, ok := writer.screens[pane]; ok {
+		screen.Resize(cols, rows)
+		return
 	}
+	writer.screens[pane] = NewScreen(cols, rows)
+}
 
-	// Write the recording file
-	err = ioutil.WriteFile(writer.specification.OutputPath, file.Bytes(), 0644)
-	if err != nil {
-		return err
+// TogglePause pauses the recording, or resumes it if it was paused. It
+// returns true if the recording is paused.
+func (writer *ShellWriter) TogglePause() bool {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.paused {
+		writer.resume()
+	} else {
+		writer.pause()
 	}
 
-	return nil
-}
\ No newline at end of file
+	return writer.paused
+}
+
+// pause stops recording the input, inserting a placeholder frame on every
+// pane if the specification asks for it.
+func (writer *ShellWriter) pause() {
+	if writer.specification.PausePlaceholder {
+		delay := writer.delay()
+		for _, pane := range writer.panes() {
+			writer.records = append(writer.records, Record{Delay: delay, Pane: pane, Content: PausedPlaceholder})
+			delay = 0
+		}
+	}
+	writer.paused = true
+}
+
+// resume starts recording the input again. The time spent paused is
+// collapsed, and every pane is repainted so the screen shows what it showed
+// when the recording resumed.
+func (writer *ShellWriter) resume() {
+	defer writer.now()
+
+	delay := 0
+	if writer.specification.PausePlaceholder {
+		delay = PausedDelay
+	}
+	for _, pane := range writer.panes() {
+		writer.records = append(writer.records, Record{Delay: delay, Pane: pane, Content: writer.screens[pane].Repaint()})
+		delay = 0
+	}
+	writer.paused = false
+}
+
+// panes returns the IDs of the tracked panes in order.
+func (writer *ShellWriter) panes() []int {
+	panes := make([]int, 0, len(writer.screens))
+	for pane := range writer.screens {
+		panes = append(panes, pane)
+	}
+	sort.Ints(panes)
+	return panes
+}
+
+// Dump writes the Recording to a YAML file on the path provided
+// by the shell specification. The file is gzip compressed if the path ends
+// in `.gz`.
+func (writer *ShellWriter) Dump() error {
+	return writer.Flush(FileSink{writer.specification.OutputPath})
+}
+
+// Flush stores the Recording on the provided sink.
+func (writer *ShellWriter) Flush(sink RecordSink) error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	return sink.WriteRecords(writer.records)
+}
@@ -48,6 +48,55 @@ func (suite *ShellWriterSuite) TestShellWriter() {
 	})
 }
 
+func (suite *ShellWriterSuite) TestShellWriterPanes() {
+	writer := NewShellWriter(suite.specification)
+	writer.Resize(0, 40, 10)
+	writer.Resize(1, 40, 10)
+	writer.Pane(0).Write([]byte("a"))
+	writer.Pane(1).Write([]byte("b"))
+	writer.Pane(1).Write([]byte("c"))
+
+	// should only append the content to records of the same pane
+	assert.Equal(suite.T(), []Record{
+		{Delay: 0, Pane: 0, Cols: 40, Rows: 10},
+		{Delay: 0, Pane: 1, Cols: 40, Rows: 10},
+		{Delay: 0, Pane: 0, Content: "a"},
+		{Delay: 0, Pane: 1, Content: "bc"},
+	}, writer.records)
+}
+
+func (suite *ShellWriterSuite) TestShellWriterPause() {
+	suite.Run("should not record while paused and repaint the screen on resume", func() {
+		writer := NewShellWriter(suite.specification)
+		writer.Track(0, 10, 2)
+		writer.Write([]byte("$ "))
+		assert.True(suite.T(), writer.TogglePause())
+		writer.Write([]byte("secret\r\n$ "))
+		time.Sleep(time.Millisecond * time.Duration(suite.specification.MinDelay + 1))
+		assert.False(suite.T(), writer.TogglePause())
+
+		assert.Equal(suite.T(), 2, len(writer.records), "should have 2 records")
+		assert.Equal(suite.T(), 0, writer.records[1].Delay, "should collapse the paused time")
+		screen := NewScreen(10, 2)
+		screen.Write([]byte(writer.records[1].Content))
+		assert.Equal(suite.T(), "$ secret\n$", screen.Text(), "should repaint the screen")
+	})
+
+	suite.Run("should insert a paused placeholder", func() {
+		specification := NewShellSpecification()
+		specification.PausePlaceholder = true
+		writer := NewShellWriter(specification)
+		writer.Track(0, 10, 2)
+		writer.Write([]byte("$ "))
+		writer.TogglePause()
+		writer.TogglePause()
+
+		assert.Equal(suite.T(), 3, len(writer.records), "should have 3 records")
+		assert.Equal(suite.T(), PausedPlaceholder, writer.records[1].Content)
+		assert.Equal(suite.T(), PausedDelay, writer.records[2].Delay)
+	})
+}
+
 // Run the test suite
 func TestShellWriterSuite(t *testing.T) {
 	suite.Run(t, new(ShellWriterSuite))